@@ -0,0 +1,170 @@
+package api
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/helmigandi/go-workout-api/internal/fitness"
+	"github.com/helmigandi/go-workout-api/internal/middleware"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/helmigandi/go-workout-api/internal/utils"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+type AnalyticsHandler struct {
+	analyticsStore store.AnalyticsStore
+	logger         *log.Logger
+}
+
+func NewAnalyticsHandler(analyticsStore store.AnalyticsStore, logger *log.Logger) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsStore: analyticsStore,
+		logger:         logger,
+	}
+}
+
+func (ah *AnalyticsHandler) readExerciseProgressQuery(r *http.Request) (store.ExerciseProgressQuery, error) {
+	qs := r.URL.Query()
+	q := store.ExerciseProgressQuery{
+		Exercise: strings.TrimSpace(chi.URLParam(r, "exercise")),
+		Bucket:   utils.ReadString(qs, "bucket", "week"),
+		Formula:  utils.ReadString(qs, "formula", fitness.FormulaEpley),
+	}
+
+	if q.Exercise == "" {
+		return q, errors.New("exercise is required")
+	}
+
+	if !slices.Contains(store.AnalyticsBuckets, q.Bucket) {
+		return q, errors.New("bucket must be one of " + strings.Join(store.AnalyticsBuckets, ", "))
+	}
+
+	if !slices.Contains(fitness.OneRepMaxFormulas, q.Formula) {
+		return q, errors.New("formula must be one of " + strings.Join(fitness.OneRepMaxFormulas, ", "))
+	}
+
+	var err error
+	q.Location, err = readLocation(qs)
+	if err != nil {
+		return q, err
+	}
+
+	q.From, q.To, err = utils.ReadDateRange(qs)
+	return q, err
+}
+
+// readLocation reads the tz query value, an IANA time zone that defaults to
+// UTC.
+func readLocation(qs url.Values) (*time.Location, error) {
+	name := utils.ReadString(qs, "tz", "UTC")
+	location, err := time.LoadLocation(name)
+	// Local is the zone of the server, which means nothing to a client
+	if err != nil || name == "Local" {
+		return nil, errors.New("tz must be an IANA time zone such as Europe/Berlin")
+	}
+	return location, nil
+}
+
+// HandleGetExerciseProgress reports the caller's progress on one exercise by
+// day, week or month, with the buckets starting at midnight in the tz query
+// value, UTC by default.
+func (ah *AnalyticsHandler) HandleGetExerciseProgress(w http.ResponseWriter, r *http.Request) {
+	q, err := ah.readExerciseProgressQuery(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	q.UserID = middleware.GetUser(r).ID
+
+	points, err := ah.analyticsStore.GetExerciseProgress(q)
+	if errors.Is(err, store.ErrUnknownExercise) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "exercise does not exist"})
+		return
+	}
+	if err != nil {
+		ah.logger.Printf("ERROR: getExerciseProgress: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	store.ConvertProgressWeights(points, unit)
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"exercise":    q.Exercise,
+		"bucket":      q.Bucket,
+		"formula":     q.Formula,
+		"timezone":    q.Location.String(),
+		"weight_unit": unit,
+		"series":      points,
+	})
+}
+
+func (ah *AnalyticsHandler) readSummaryQuery(r *http.Request) (store.SummaryQuery, error) {
+	qs := r.URL.Query()
+	q := store.SummaryQuery{
+		Period: utils.ReadString(qs, "period", "week"),
+		Date:   time.Now(),
+	}
+
+	if !slices.Contains(store.SummaryPeriods, q.Period) {
+		return q, errors.New("period must be one of " + strings.Join(store.SummaryPeriods, ", "))
+	}
+
+	var err error
+	q.Location, err = readLocation(qs)
+	if err != nil {
+		return q, err
+	}
+
+	if qs.Has("date") {
+		date, err := time.ParseInLocation(time.DateOnly, qs.Get("date"), q.Location)
+		if err != nil {
+			return q, errors.New("date must be a date (YYYY-MM-DD)")
+		}
+		q.Date = date
+	}
+
+	q.TopExercises, err = utils.ReadInt(qs, "top", 5)
+	if err != nil {
+		return q, err
+	}
+	if q.TopExercises < 1 || q.TopExercises > 50 {
+		return q, errors.New("top must be between 1 and 50")
+	}
+
+	return q, nil
+}
+
+// HandleGetSummary reports the caller's training for the week or month that
+// contains the date query param, compared with the period before it.
+func (ah *AnalyticsHandler) HandleGetSummary(w http.ResponseWriter, r *http.Request) {
+	q, err := ah.readSummaryQuery(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	q.UserID = middleware.GetUser(r).ID
+
+	summary, err := ah.analyticsStore.GetTrainingSummary(q)
+	if err != nil {
+		ah.logger.Printf("ERROR: getTrainingSummary: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	summary.ConvertWeights(unit)
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"summary": summary, "weight_unit": unit})
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/helmigandi/go-workout-api/internal/middleware"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/helmigandi/go-workout-api/internal/utils"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+type exerciseRequest struct {
+	Name             string   `json:"name"`
+	Category         string   `json:"category"`
+	PrimaryMuscles   []string `json:"primary_muscles"`
+	SecondaryMuscles []string `json:"secondary_muscles"`
+	Equipment        string   `json:"equipment"`
+}
+
+type ExerciseHandler struct {
+	exerciseStore store.ExerciseStore
+	logger        *log.Logger
+}
+
+func NewExerciseHandler(exerciseStore store.ExerciseStore, logger *log.Logger) *ExerciseHandler {
+	return &ExerciseHandler{
+		exerciseStore: exerciseStore,
+		logger:        logger,
+	}
+}
+
+func (eh *ExerciseHandler) validateExerciseRequest(req *exerciseRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(req.Name) > 255 {
+		return errors.New("name must be less than 255 characters")
+	}
+
+	if !slices.Contains(store.ExerciseCategories, req.Category) {
+		return errors.New("category must be one of " + strings.Join(store.ExerciseCategories, ", "))
+	}
+
+	for _, muscle := range append(slices.Clone(req.PrimaryMuscles), req.SecondaryMuscles...) {
+		if !slices.Contains(store.MuscleGroups, muscle) {
+			return errors.New("unknown muscle group " + muscle)
+		}
+	}
+
+	if len(req.Equipment) > 100 {
+		return errors.New("equipment must be less than 100 characters")
+	}
+
+	return nil
+}
+
+// getVisibleExercise loads the exercise from the id param and writes the error
+// response itself when the caller cannot see it.
+func (eh *ExerciseHandler) getVisibleExercise(w http.ResponseWriter, r *http.Request) *store.Exercise {
+	exerciseID, err := utils.ReadIDParam(r)
+	if err != nil {
+		eh.logger.Printf("ERROR: read id param: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid exercise id"})
+		return nil
+	}
+
+	exercise, err := eh.exerciseStore.GetExerciseByID(exerciseID)
+	if err != nil {
+		eh.logger.Printf("ERROR: getExerciseByID: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+
+	user := middleware.GetUser(r)
+	if exercise == nil || (exercise.IsCustom() && *exercise.UserID != user.ID) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "exercise does not exist"})
+		return nil
+	}
+
+	return exercise
+}
+
+func (eh *ExerciseHandler) HandleListExercises(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	filter := store.ExerciseFilter{
+		UserID:   middleware.GetUser(r).ID,
+		Name:     utils.ReadString(qs, "name", ""),
+		Category: utils.ReadString(qs, "category", ""),
+		Muscle:   utils.ReadString(qs, "muscle", ""),
+	}
+
+	exercises, err := eh.exerciseStore.ListExercises(filter)
+	if err != nil {
+		eh.logger.Printf("ERROR: listExercises: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"exercises": exercises})
+}
+
+func (eh *ExerciseHandler) HandleGetExerciseByID(w http.ResponseWriter, r *http.Request) {
+	exercise := eh.getVisibleExercise(w, r)
+	if exercise == nil {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"exercise": exercise})
+}
+
+func (eh *ExerciseHandler) HandleCreateExercise(w http.ResponseWriter, r *http.Request) {
+	var request exerciseRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		eh.logger.Printf("ERROR: decoding create exercise: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+
+	err = eh.validateExerciseRequest(&request)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	user := middleware.GetUser(r)
+	exercise := &store.Exercise{
+		UserID:           &user.ID,
+		Name:             request.Name,
+		Category:         request.Category,
+		PrimaryMuscles:   request.PrimaryMuscles,
+		SecondaryMuscles: request.SecondaryMuscles,
+		Equipment:        request.Equipment,
+	}
+
+	err = eh.exerciseStore.CreateExercise(exercise)
+	if errors.Is(err, store.ErrDuplicateExercise) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		eh.logger.Printf("ERROR: create exercise: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create exercise"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"exercise": exercise})
+}
+
+func (eh *ExerciseHandler) HandleUpdateExercise(w http.ResponseWriter, r *http.Request) {
+	exercise := eh.getVisibleExercise(w, r)
+	if exercise == nil {
+		return
+	}
+
+	if !exercise.IsCustom() {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "built-in exercises cannot be modified"})
+		return
+	}
+
+	request := exerciseRequest{
+		Name:             exercise.Name,
+		Category:         exercise.Category,
+		PrimaryMuscles:   exercise.PrimaryMuscles,
+		SecondaryMuscles: exercise.SecondaryMuscles,
+		Equipment:        exercise.Equipment,
+	}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		eh.logger.Printf("ERROR: decoding update exercise: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+
+	err = eh.validateExerciseRequest(&request)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	exercise.Name = request.Name
+	exercise.Category = request.Category
+	exercise.PrimaryMuscles = request.PrimaryMuscles
+	exercise.SecondaryMuscles = request.SecondaryMuscles
+	exercise.Equipment = request.Equipment
+
+	err = eh.exerciseStore.UpdateExercise(exercise)
+	if errors.Is(err, store.ErrDuplicateExercise) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		eh.logger.Printf("ERROR: updatingExercise: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"exercise": exercise})
+}
+
+func (eh *ExerciseHandler) HandleDeleteExercise(w http.ResponseWriter, r *http.Request) {
+	exercise := eh.getVisibleExercise(w, r)
+	if exercise == nil {
+		return
+	}
+
+	if !exercise.IsCustom() {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "built-in exercises cannot be deleted"})
+		return
+	}
+
+	err := eh.exerciseStore.DeleteExercise(int64(exercise.ID))
+	if err != nil {
+		eh.logger.Printf("ERROR: deleteExercise: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/helmigandi/go-workout-api/internal/importer"
+	"github.com/helmigandi/go-workout-api/internal/middleware"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/helmigandi/go-workout-api/internal/utils"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// maxImportBytes limits the size of an uploaded file.
+const maxImportBytes = 32 << 20
+
+// uploadTimeout is how long a client has to send an uploaded file.
+const uploadTimeout = 10 * time.Minute
+
+type ImportHandler struct {
+	importer *importer.Importer
+	jobStore store.ImportJobStore
+	logger   *log.Logger
+}
+
+func NewImportHandler(importer *importer.Importer, jobStore store.ImportJobStore, logger *log.Logger) *ImportHandler {
+	return &ImportHandler{
+		importer: importer,
+		jobStore: jobStore,
+		logger:   logger,
+	}
+}
+
+// HandleImportCSV starts importing the workouts of a CSV file. The request is
+// multipart/form-data with the file in a "file" part and, optionally, the
+// column mapping as JSON in a "mapping" part. The file is written to disk as
+// it arrives and imported in the background, the job returned can be polled
+// at GET /imports/{id}.
+func (ih *ImportHandler) HandleImportCSV(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	// large files take longer to upload than the server's read timeout
+	_ = http.NewResponseController(w).SetReadDeadline(time.Now().Add(uploadTimeout))
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "the request must be multipart/form-data"})
+		return
+	}
+
+	mapping := importer.DefaultMapping()
+	filename, path := "", ""
+	defer func() {
+		// the importer owns the file once it started
+		if path != "" {
+			os.Remove(path)
+		}
+	}()
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			writeUploadError(w, ih.logger, err)
+			return
+		}
+
+		switch part.FormName() {
+		case "mapping":
+			err = json.NewDecoder(part).Decode(&mapping)
+			if err != nil {
+				utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "mapping must be a JSON object"})
+				return
+			}
+		case "file":
+			if path != "" {
+				utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "only one file can be imported at a time"})
+				return
+			}
+			filename = part.FileName()
+			path, err = saveUpload(part)
+			if err != nil {
+				writeUploadError(w, ih.logger, err)
+				return
+			}
+		}
+	}
+
+	if path == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "file is required"})
+		return
+	}
+	if mapping.WeightUnit == "" {
+		mapping.WeightUnit = user.WeightUnit
+	}
+
+	job, err := ih.importer.StartCSV(user.ID, filename, path, mapping)
+	path = ""
+	if errors.Is(err, importer.ErrInvalidMapping) {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, importer.ErrTooManyJobs) {
+		utils.WriteJSON(w, http.StatusTooManyRequests, utils.Envelope{"error": fmt.Sprintf("at most %d imports can run at the same time, wait for one to finish", importer.MaxJobsPerUser)})
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		utils.WriteJSON(w, http.StatusServiceUnavailable, utils.Envelope{"error": "the server is shutting down"})
+		return
+	}
+	if err != nil {
+		ih.logger.Printf("ERROR: startCSV: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/imports/%d", job.ID))
+	utils.WriteJSON(w, http.StatusAccepted, utils.Envelope{"import_job": job})
+}
+
+// saveUpload copies an uploaded file to a temporary file and returns its path.
+func saveUpload(part io.Reader) (string, error) {
+	file, err := os.CreateTemp("", "import-*.csv")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, part)
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+// writeUploadError answers a request whose upload could not be read.
+func writeUploadError(w http.ResponseWriter, logger *log.Logger, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		utils.WriteJSON(w, http.StatusRequestEntityTooLarge, utils.Envelope{"error": fmt.Sprintf("the file must not be larger than %d MB", maxBytesErr.Limit>>20)})
+		return
+	}
+	logger.Printf("ERROR: reading upload: %v\n", err)
+	utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+}
+
+// HandleGetImportJob reports the status and progress of an import job of the
+// caller.
+func (ih *ImportHandler) HandleGetImportJob(w http.ResponseWriter, r *http.Request) {
+	jobID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid import job id"})
+		return
+	}
+
+	job, err := ih.jobStore.GetImportJob(jobID)
+	if err != nil {
+		ih.logger.Printf("ERROR: getImportJob: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if job == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "import job does not exist"})
+		return
+	}
+
+	if job.UserID != middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "Forbidden"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"import_job": job})
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"github.com/helmigandi/go-workout-api/internal/middleware"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/helmigandi/go-workout-api/internal/utils"
+	"log"
+	"net/http"
+)
+
+type PersonalRecordHandler struct {
+	recordStore store.PersonalRecordStore
+	logger      *log.Logger
+}
+
+func NewPersonalRecordHandler(recordStore store.PersonalRecordStore, logger *log.Logger) *PersonalRecordHandler {
+	return &PersonalRecordHandler{
+		recordStore: recordStore,
+		logger:      logger,
+	}
+}
+
+// HandleListPersonalRecords returns the caller's record history, optionally
+// narrowed to a single exercise by name.
+func (ph *PersonalRecordHandler) HandleListPersonalRecords(w http.ResponseWriter, r *http.Request) {
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	exercise := utils.ReadString(r.URL.Query(), "exercise", "")
+
+	records, err := ph.recordStore.ListPersonalRecords(middleware.GetUser(r).ID, exercise)
+	if err != nil {
+		ph.logger.Printf("ERROR: listPersonalRecords: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	convertRecordWeights(records, unit)
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"personal_records": records})
+}
@@ -0,0 +1,375 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/helmigandi/go-workout-api/internal/middleware"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/helmigandi/go-workout-api/internal/utils"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type programRequest struct {
+	Name          string             `json:"name"`
+	Description   string             `json:"description"`
+	DurationWeeks int                `json:"duration_weeks"`
+	Days          []store.ProgramDay `json:"days"`
+}
+
+type enrollRequest struct {
+	StartDate string `json:"start_date"`
+}
+
+type completeSessionRequest struct {
+	WorkoutID int64 `json:"workout_id"`
+}
+
+type ProgramHandler struct {
+	programStore store.ProgramStore
+	workoutStore store.WorkoutStore
+	logger       *log.Logger
+}
+
+func NewProgramHandler(programStore store.ProgramStore, workoutStore store.WorkoutStore, logger *log.Logger) *ProgramHandler {
+	return &ProgramHandler{
+		programStore: programStore,
+		workoutStore: workoutStore,
+		logger:       logger,
+	}
+}
+
+func (ph *ProgramHandler) validateProgramRequest(req *programRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(req.Name) > 255 {
+		return errors.New("name must be less than 255 characters")
+	}
+
+	if req.DurationWeeks < 1 || req.DurationWeeks > 104 {
+		return errors.New("duration_weeks must be between 1 and 104")
+	}
+
+	if len(req.Days) == 0 {
+		return errors.New("a program needs at least one day")
+	}
+
+	scheduled := make(map[[2]int]bool, len(req.Days))
+	for _, day := range req.Days {
+		if day.WeekNumber < 1 || day.WeekNumber > req.DurationWeeks {
+			return fmt.Errorf("week_number must be between 1 and %d", req.DurationWeeks)
+		}
+
+		if day.DayNumber < 1 || day.DayNumber > 7 {
+			return errors.New("day_number must be between 1 and 7")
+		}
+
+		if day.TemplateID <= 0 {
+			return errors.New("every day needs a template_id")
+		}
+
+		key := [2]int{day.WeekNumber, day.DayNumber}
+		if scheduled[key] {
+			return fmt.Errorf("week %d day %d is scheduled twice", day.WeekNumber, day.DayNumber)
+		}
+		scheduled[key] = true
+	}
+
+	return nil
+}
+
+// getOwnedProgram loads the program from the id param and writes the error
+// response itself when it does not belong to the caller.
+func (ph *ProgramHandler) getOwnedProgram(w http.ResponseWriter, r *http.Request) *store.Program {
+	programID, err := utils.ReadIDParam(r)
+	if err != nil {
+		ph.logger.Printf("ERROR: read id param: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid program id"})
+		return nil
+	}
+
+	program, err := ph.programStore.GetProgramByID(programID)
+	if err != nil {
+		ph.logger.Printf("ERROR: getProgramByID: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+
+	if program == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "program does not exist"})
+		return nil
+	}
+
+	if program.UserID != middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "Forbidden"})
+		return nil
+	}
+
+	return program
+}
+
+// getOwnedEnrollment loads the enrollment from the id param together with
+// its sessions and writes the error response itself when it does not belong
+// to the caller.
+func (ph *ProgramHandler) getOwnedEnrollment(w http.ResponseWriter, r *http.Request) (*store.Enrollment, []*store.ProgramSession) {
+	enrollmentID, err := utils.ReadIDParam(r)
+	if err != nil {
+		ph.logger.Printf("ERROR: read id param: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid enrollment id"})
+		return nil, nil
+	}
+
+	enrollment, err := ph.programStore.GetEnrollmentByID(enrollmentID)
+	if err != nil {
+		ph.logger.Printf("ERROR: getEnrollmentByID: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, nil
+	}
+
+	if enrollment == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "enrollment does not exist"})
+		return nil, nil
+	}
+
+	if enrollment.UserID != middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "Forbidden"})
+		return nil, nil
+	}
+
+	sessions, err := ph.programStore.ListSessions(enrollmentID)
+	if err != nil {
+		ph.logger.Printf("ERROR: listSessions: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, nil
+	}
+
+	return enrollment, sessions
+}
+
+// readToday returns the date query param, defaulting to the current date.
+func (ph *ProgramHandler) readToday(r *http.Request) (time.Time, error) {
+	date, _, err := utils.ReadDate(r.URL.Query(), "date")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if date == nil {
+		return time.Now(), nil
+	}
+
+	return *date, nil
+}
+
+func (ph *ProgramHandler) HandleListPrograms(w http.ResponseWriter, r *http.Request) {
+	programs, err := ph.programStore.ListPrograms(middleware.GetUser(r).ID)
+	if err != nil {
+		ph.logger.Printf("ERROR: listPrograms: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"programs": programs})
+}
+
+func (ph *ProgramHandler) HandleGetProgramByID(w http.ResponseWriter, r *http.Request) {
+	program := ph.getOwnedProgram(w, r)
+	if program == nil {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"program": program})
+}
+
+func (ph *ProgramHandler) HandleCreateProgram(w http.ResponseWriter, r *http.Request) {
+	var request programRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		ph.logger.Printf("ERROR: decoding create program: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+
+	err = ph.validateProgramRequest(&request)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	program := &store.Program{
+		UserID:        middleware.GetUser(r).ID,
+		Name:          request.Name,
+		Description:   request.Description,
+		DurationWeeks: request.DurationWeeks,
+		Days:          request.Days,
+	}
+
+	err = ph.programStore.CreateProgram(program)
+	if errors.Is(err, store.ErrUnknownTemplate) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ph.logger.Printf("ERROR: create program: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create program"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"program": program})
+}
+
+func (ph *ProgramHandler) HandleDeleteProgram(w http.ResponseWriter, r *http.Request) {
+	program := ph.getOwnedProgram(w, r)
+	if program == nil {
+		return
+	}
+
+	err := ph.programStore.DeleteProgram(int64(program.ID))
+	if err != nil {
+		ph.logger.Printf("ERROR: deleteProgram: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ph *ProgramHandler) HandleEnroll(w http.ResponseWriter, r *http.Request) {
+	program := ph.getOwnedProgram(w, r)
+	if program == nil {
+		return
+	}
+
+	var request enrollRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		ph.logger.Printf("ERROR: decoding enroll request: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+
+	startDate, err := time.Parse(time.DateOnly, request.StartDate)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "start_date must be a date (YYYY-MM-DD)"})
+		return
+	}
+
+	enrollment := &store.Enrollment{
+		ProgramID: program.ID,
+		UserID:    program.UserID,
+		StartDate: startDate,
+	}
+
+	err = ph.programStore.CreateEnrollment(enrollment)
+	if err != nil {
+		ph.logger.Printf("ERROR: create enrollment: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to enroll"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"enrollment": enrollment})
+}
+
+func (ph *ProgramHandler) HandleListEnrollments(w http.ResponseWriter, r *http.Request) {
+	enrollments, err := ph.programStore.ListEnrollments(middleware.GetUser(r).ID)
+	if err != nil {
+		ph.logger.Printf("ERROR: listEnrollments: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"enrollments": enrollments})
+}
+
+// HandleGetTodaySession returns the session scheduled for today, or for the
+// date query param, with a null session on rest days.
+func (ph *ProgramHandler) HandleGetTodaySession(w http.ResponseWriter, r *http.Request) {
+	today, err := ph.readToday(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	enrollment, sessions := ph.getOwnedEnrollment(w, r)
+	if enrollment == nil {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"session": store.SessionOn(sessions, today)})
+}
+
+func (ph *ProgramHandler) HandleGetProgress(w http.ResponseWriter, r *http.Request) {
+	today, err := ph.readToday(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	enrollment, sessions := ph.getOwnedEnrollment(w, r)
+	if enrollment == nil {
+		return
+	}
+
+	program, err := ph.programStore.GetProgramByID(int64(enrollment.ProgramID))
+	if err != nil || program == nil {
+		ph.logger.Printf("ERROR: getProgramByID: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	progress := store.CalculateProgress(enrollment, program.DurationWeeks, sessions, today)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"progress": progress, "sessions": sessions})
+}
+
+// HandleCompleteSession links one of the caller's logged workouts to a day of
+// the enrolled program.
+func (ph *ProgramHandler) HandleCompleteSession(w http.ResponseWriter, r *http.Request) {
+	dayID, err := utils.ReadInt64Param(r, "dayID")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid program day id"})
+		return
+	}
+
+	enrollment, _ := ph.getOwnedEnrollment(w, r)
+	if enrollment == nil {
+		return
+	}
+
+	var request completeSessionRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		ph.logger.Printf("ERROR: decoding complete session: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+
+	owner, err := ph.workoutStore.GetWorkoutOwner(request.WorkoutID)
+	if err != nil {
+		ph.logger.Printf("ERROR: getWorkoutOwner: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if owner != enrollment.UserID {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "workout does not exist"})
+		return
+	}
+
+	session, err := ph.programStore.CompleteSession(int64(enrollment.ID), dayID, request.WorkoutID)
+	if errors.Is(err, store.ErrUnknownProgramDay) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ph.logger.Printf("ERROR: completeSession: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"session": session})
+}
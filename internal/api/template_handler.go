@@ -0,0 +1,292 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/helmigandi/go-workout-api/internal/middleware"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/helmigandi/go-workout-api/internal/utils"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type templateRequest struct {
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	Exercises   []store.TemplateExercise `json:"exercises"`
+}
+
+type startTemplateRequest struct {
+	Title       *string    `json:"title"`
+	PerformedAt *time.Time `json:"performed_at"`
+}
+
+type TemplateHandler struct {
+	templateStore store.TemplateStore
+	workoutStore  store.WorkoutStore
+	logger        *log.Logger
+}
+
+func NewTemplateHandler(templateStore store.TemplateStore, workoutStore store.WorkoutStore, logger *log.Logger) *TemplateHandler {
+	return &TemplateHandler{
+		templateStore: templateStore,
+		workoutStore:  workoutStore,
+		logger:        logger,
+	}
+}
+
+func (th *TemplateHandler) validateTemplateRequest(req *templateRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(req.Name) > 255 {
+		return errors.New("name must be less than 255 characters")
+	}
+
+	for _, exercise := range req.Exercises {
+		if exercise.ExerciseID == nil && strings.TrimSpace(exercise.ExerciseName) == "" {
+			return errors.New("every exercise needs an exercise_id or exercise_name")
+		}
+
+		if exercise.TargetSets < 1 {
+			return errors.New("target_sets must be at least 1")
+		}
+
+		if (exercise.TargetReps == nil) == (exercise.TargetDurationSeconds == nil) {
+			return errors.New("every exercise needs either target_reps or target_duration_seconds")
+		}
+	}
+
+	return nil
+}
+
+// getOwnedTemplate loads the template from the id param and writes the error
+// response itself when it does not belong to the caller.
+func (th *TemplateHandler) getOwnedTemplate(w http.ResponseWriter, r *http.Request) *store.WorkoutTemplate {
+	templateID, err := utils.ReadIDParam(r)
+	if err != nil {
+		th.logger.Printf("ERROR: read id param: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid template id"})
+		return nil
+	}
+
+	template, err := th.templateStore.GetTemplateByID(templateID)
+	if err != nil {
+		th.logger.Printf("ERROR: getTemplateByID: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+
+	if template == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "template does not exist"})
+		return nil
+	}
+
+	if template.UserID != middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "Forbidden"})
+		return nil
+	}
+
+	return template
+}
+
+func (th *TemplateHandler) HandleListTemplates(w http.ResponseWriter, r *http.Request) {
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	templates, err := th.templateStore.ListTemplates(middleware.GetUser(r).ID)
+	if err != nil {
+		th.logger.Printf("ERROR: listTemplates: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	for _, template := range templates {
+		template.ConvertWeights(unit)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"templates": templates})
+}
+
+func (th *TemplateHandler) HandleGetTemplateByID(w http.ResponseWriter, r *http.Request) {
+	template := th.getOwnedTemplate(w, r)
+	if template == nil {
+		return
+	}
+
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	template.ConvertWeights(unit)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"template": template})
+}
+
+func (th *TemplateHandler) HandleCreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var request templateRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		th.logger.Printf("ERROR: decoding create template: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+
+	err = th.validateTemplateRequest(&request)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	user := middleware.GetUser(r)
+	defaultTemplateWeightUnits(request.Exercises, user.WeightUnit)
+	template := &store.WorkoutTemplate{
+		UserID:      user.ID,
+		Name:        request.Name,
+		Description: request.Description,
+		Exercises:   request.Exercises,
+	}
+
+	err = th.templateStore.CreateTemplate(template)
+	if errors.Is(err, store.ErrUnknownExercise) || errors.Is(err, store.ErrUnknownWeightUnit) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		th.logger.Printf("ERROR: create template: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create template"})
+		return
+	}
+
+	template.ConvertWeights(unit)
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"template": template})
+}
+
+func (th *TemplateHandler) HandleUpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	template := th.getOwnedTemplate(w, r)
+	if template == nil {
+		return
+	}
+
+	request := templateRequest{
+		Name:        template.Name,
+		Description: template.Description,
+		Exercises:   template.Exercises,
+	}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		th.logger.Printf("ERROR: decoding update template: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+
+	err = th.validateTemplateRequest(&request)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	defaultTemplateWeightUnits(request.Exercises, middleware.GetUser(r).WeightUnit)
+	template.Name = request.Name
+	template.Description = request.Description
+	template.Exercises = request.Exercises
+
+	err = th.templateStore.UpdateTemplate(template)
+	if errors.Is(err, store.ErrUnknownExercise) || errors.Is(err, store.ErrUnknownWeightUnit) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		th.logger.Printf("ERROR: updatingTemplate: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	template.ConvertWeights(unit)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"template": template})
+}
+
+func (th *TemplateHandler) HandleDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	template := th.getOwnedTemplate(w, r)
+	if template == nil {
+		return
+	}
+
+	err := th.templateStore.DeleteTemplate(int64(template.ID))
+	if errors.Is(err, store.ErrTemplateInUse) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		th.logger.Printf("ERROR: deleteTemplate: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleStartTemplate logs a new workout for the caller from the template.
+// The body is optional and may override the title and performed_at.
+func (th *TemplateHandler) HandleStartTemplate(w http.ResponseWriter, r *http.Request) {
+	template := th.getOwnedTemplate(w, r)
+	if template == nil {
+		return
+	}
+
+	var request startTemplateRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil && !errors.Is(err, io.EOF) {
+		th.logger.Printf("ERROR: decoding start template: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	workout := template.NewWorkout(middleware.GetUser(r).ID)
+	if request.Title != nil {
+		workout.Title = *request.Title
+	}
+	if request.PerformedAt != nil {
+		workout.PerformedAt = *request.PerformedAt
+	}
+
+	createdWorkout, err := th.workoutStore.CreateWorkout(workout)
+	if errors.Is(err, store.ErrUnknownExercise) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		th.logger.Printf("ERROR: create workout from template: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create workout"})
+		return
+	}
+
+	createdWorkout.ConvertWeights(unit)
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"workout": createdWorkout})
+}
@@ -3,18 +3,27 @@ package api
 import (
 	"encoding/json"
 	"errors"
+	"github.com/helmigandi/go-workout-api/internal/fitness"
+	"github.com/helmigandi/go-workout-api/internal/middleware"
 	"github.com/helmigandi/go-workout-api/internal/store"
 	"github.com/helmigandi/go-workout-api/internal/utils"
 	"log"
 	"net/http"
 	"regexp"
+	"slices"
 )
 
 type registerUserRequest struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Bio      string `json:"bio"`
+	Username   string `json:"username"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	Bio        string `json:"bio"`
+	WeightUnit string `json:"weight_unit"`
+}
+
+type updateUserRequest struct {
+	Bio        *string `json:"bio"`
+	WeightUnit *string `json:"weight_unit"`
 }
 
 type UserHandler struct {
@@ -55,6 +64,10 @@ func (u *UserHandler) validateRegisterRequest(req *registerUserRequest) error {
 		return errors.New("password must be at least 6 characters")
 	}
 
+	if req.WeightUnit != "" && !slices.Contains(fitness.WeightUnits, req.WeightUnit) {
+		return errors.New("weight_unit must be kg or lb")
+	}
+
 	return nil
 }
 
@@ -76,8 +89,9 @@ func (u *UserHandler) HandleRegisterUser(w http.ResponseWriter, r *http.Request)
 	}
 
 	user := &store.User{
-		Username: request.Username,
-		Email:    request.Email,
+		Username:   request.Username,
+		Email:      request.Email,
+		WeightUnit: request.WeightUnit,
 	}
 
 	if request.Bio != "" {
@@ -100,3 +114,35 @@ func (u *UserHandler) HandleRegisterUser(w http.ResponseWriter, r *http.Request)
 
 	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"user": user})
 }
+
+// HandleUpdateCurrentUser changes the profile and preferences of the caller.
+func (u *UserHandler) HandleUpdateCurrentUser(w http.ResponseWriter, r *http.Request) {
+	var request updateUserRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		u.logger.Printf("ERROR: decoding update user request: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+
+	user := *middleware.GetUser(r)
+	if request.Bio != nil {
+		user.Bio = *request.Bio
+	}
+	if request.WeightUnit != nil {
+		if !slices.Contains(fitness.WeightUnits, *request.WeightUnit) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "weight_unit must be kg or lb"})
+			return
+		}
+		user.WeightUnit = *request.WeightUnit
+	}
+
+	err = u.userStore.UpdateUser(&user)
+	if err != nil {
+		u.logger.Printf("ERROR: updating user: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"user": user})
+}
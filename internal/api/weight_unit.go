@@ -0,0 +1,48 @@
+package api
+
+import (
+	"errors"
+	"github.com/helmigandi/go-workout-api/internal/fitness"
+	"github.com/helmigandi/go-workout-api/internal/middleware"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/helmigandi/go-workout-api/internal/utils"
+	"net/http"
+	"slices"
+)
+
+// readWeightUnit returns the unit weights are shown in, the units query param
+// when given and otherwise the preference of the user.
+func readWeightUnit(r *http.Request) (string, error) {
+	unit := utils.ReadString(r.URL.Query(), "units", middleware.GetUser(r).WeightUnit)
+	if unit == "" {
+		return fitness.UnitKilogram, nil
+	}
+	if !slices.Contains(fitness.WeightUnits, unit) {
+		return "", errors.New("units must be kg or lb")
+	}
+	return unit, nil
+}
+
+// defaultEntryWeightUnits takes entries sent without a weight unit to be
+// logged in the preferred unit of the user.
+func defaultEntryWeightUnits(entries []store.WorkoutEntry, unit string) {
+	for i := range entries {
+		if entries[i].WeightUnit == "" {
+			entries[i].WeightUnit = unit
+		}
+	}
+}
+
+func defaultTemplateWeightUnits(exercises []store.TemplateExercise, unit string) {
+	for i := range exercises {
+		if exercises[i].WeightUnit == "" {
+			exercises[i].WeightUnit = unit
+		}
+	}
+}
+
+func convertRecordWeights(records []store.PersonalRecord, unit string) {
+	for i := range records {
+		records[i].ConvertWeights(unit)
+	}
+}
@@ -0,0 +1,341 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/helmigandi/go-workout-api/internal/middleware"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/helmigandi/go-workout-api/internal/utils"
+	"io"
+	"net/http"
+	"slices"
+)
+
+type reorderEntriesRequest struct {
+	EntryIDs []int64 `json:"entry_ids"`
+}
+
+// getOwnedWorkout loads the workout from the id param and writes the error
+// response itself when it does not belong to the caller.
+func (wh *WorkoutHandler) getOwnedWorkout(w http.ResponseWriter, r *http.Request) *store.Workout {
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		wh.logger.Printf("ERROR: read id param: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return nil
+	}
+
+	workout, err := wh.workoutStore.GetWorkoutByID(workoutID)
+	if err != nil {
+		wh.logger.Printf("ERROR: getWorkoutByID: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+
+	if workout == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout does not exist"})
+		return nil
+	}
+
+	if workout.UserID != middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "Forbidden"})
+		return nil
+	}
+
+	return workout
+}
+
+// decodeEntry decodes the body onto entry, so fields missing from the body
+// keep their current value, and reports which fields were sent.
+func (wh *WorkoutHandler) decodeEntry(r *http.Request, entry *store.WorkoutEntry) (map[string]json.RawMessage, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(body, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := fields["set_details"]; ok {
+		// decoding into the current sets would keep their fields
+		entry.SetDetails = nil
+	}
+
+	return fields, json.Unmarshal(body, entry)
+}
+
+// applyEntryDefaults interprets weights sent without a unit in the preferred
+// unit of the user and re-derives the sets when only the flat fields changed.
+// currentUnit is the unit of the weights the entry had before the patch.
+func (wh *WorkoutHandler) applyEntryDefaults(entry *store.WorkoutEntry, fields map[string]json.RawMessage, currentUnit string, user *store.User) {
+	_, hasSets := fields["set_details"]
+	_, hasUnit := fields["weight_unit"]
+	_, hasWeight := fields["weight"]
+
+	if !hasSets {
+		for _, field := range []string{"sets", "reps", "weight", "duration_seconds"} {
+			if _, ok := fields[field]; ok {
+				entry.SetDetails = nil
+				break
+			}
+		}
+	}
+
+	switch {
+	case !hasSets && !hasWeight:
+		// the unit only applies to weights sent along with it
+		entry.WeightUnit = currentUnit
+	case !hasUnit:
+		entry.WeightUnit = user.WeightUnit
+	}
+}
+
+// writeEntryError answers a failed entry change and reports whether there
+// was an error at all.
+func (wh *WorkoutHandler) writeEntryError(w http.ResponseWriter, err error, action string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, store.ErrEditConflict):
+		writeEditConflict(w)
+	case errors.Is(err, sql.ErrNoRows):
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "entry does not exist"})
+	case isClientError(err):
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+	default:
+		wh.logger.Printf("ERROR: %s: %v\n", action, err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+	}
+	return true
+}
+
+// writeEntry reloads the workout after an entry changed, so the personal
+// records are detected against the whole workout, and writes the entry with
+// its weights in unit.
+func (wh *WorkoutHandler) writeEntry(w http.ResponseWriter, workoutID int64, entryID int, unit string, status int) {
+	workout, err := wh.workoutStore.GetWorkoutByID(workoutID)
+	if err != nil || workout == nil {
+		wh.logger.Printf("ERROR: getWorkoutByID: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	records := wh.detectPersonalRecords(workout)
+	workout.ConvertWeights(unit)
+	convertRecordWeights(records, unit)
+
+	i := slices.IndexFunc(workout.Entries, func(entry store.WorkoutEntry) bool { return entry.ID == entryID })
+	if i < 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "entry does not exist"})
+		return
+	}
+
+	w.Header().Set("ETag", utils.ETag(workout.Version))
+	utils.WriteJSON(w, status, utils.Envelope{"entry": workout.Entries[i], "personal_records": records})
+}
+
+// HandleCreateEntry appends a single entry to a workout, or inserts it at
+// the order_index from the body, moving the entries from there on back.
+func (wh *WorkoutHandler) HandleCreateEntry(w http.ResponseWriter, r *http.Request) {
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	workout := wh.getOwnedWorkout(w, r)
+	if workout == nil || !wh.checkIfMatch(w, r, workout) {
+		return
+	}
+
+	var entry store.WorkoutEntry
+	fields, err := wh.decodeEntry(r, &entry)
+	if err != nil {
+		wh.logger.Printf("ERROR: decoding create entry: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+	entry.ID = 0
+
+	if _, ok := fields["order_index"]; !ok {
+		entry.OrderIndex = 1
+		for _, existing := range workout.Entries {
+			entry.OrderIndex = max(entry.OrderIndex, existing.OrderIndex+1)
+		}
+	}
+	for i := range workout.Entries {
+		if workout.Entries[i].OrderIndex >= entry.OrderIndex {
+			workout.Entries[i].OrderIndex++
+		}
+	}
+	if entry.WeightUnit == "" {
+		entry.WeightUnit = middleware.GetUser(r).WeightUnit
+	}
+
+	err = entry.Validate()
+	if err == nil {
+		workout.Entries = append(workout.Entries, entry)
+		err = workout.ValidateGroups()
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = wh.workoutStore.CreateEntry(workout, &entry, middleware.GetUser(r).ID)
+	if wh.writeEntryError(w, err, "createEntry") {
+		return
+	}
+
+	wh.writeEntry(w, int64(workout.ID), entry.ID, unit, http.StatusCreated)
+}
+
+// HandleUpdateEntry changes the fields of a single entry that are present in
+// the body, keeping the entry id.
+func (wh *WorkoutHandler) HandleUpdateEntry(w http.ResponseWriter, r *http.Request) {
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	workout := wh.getOwnedWorkout(w, r)
+	if workout == nil || !wh.checkIfMatch(w, r, workout) {
+		return
+	}
+
+	entryID, err := utils.ReadInt64Param(r, "entryID")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid entry id"})
+		return
+	}
+
+	i := slices.IndexFunc(workout.Entries, func(entry store.WorkoutEntry) bool { return int64(entry.ID) == entryID })
+	if i < 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "entry does not exist"})
+		return
+	}
+
+	entry := workout.Entries[i]
+	entry.SetDetails = slices.Clone(entry.SetDetails)
+	fields, err := wh.decodeEntry(r, &entry)
+	if err != nil {
+		wh.logger.Printf("ERROR: decoding update entry: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+	entry.ID = workout.Entries[i].ID
+	wh.applyEntryDefaults(&entry, fields, workout.Entries[i].WeightUnit, middleware.GetUser(r))
+
+	err = entry.Validate()
+	if err == nil {
+		workout.Entries[i] = entry
+		err = workout.ValidateGroups()
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = wh.workoutStore.UpdateEntry(workout, &entry, middleware.GetUser(r).ID)
+	if wh.writeEntryError(w, err, "updateEntry") {
+		return
+	}
+
+	wh.writeEntry(w, int64(workout.ID), entry.ID, unit, http.StatusOK)
+}
+
+// HandleDeleteEntry removes a single entry from a workout. A group left with
+// one entry is dissolved.
+func (wh *WorkoutHandler) HandleDeleteEntry(w http.ResponseWriter, r *http.Request) {
+	workout := wh.getOwnedWorkout(w, r)
+	if workout == nil || !wh.checkIfMatch(w, r, workout) {
+		return
+	}
+
+	entryID, err := utils.ReadInt64Param(r, "entryID")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid entry id"})
+		return
+	}
+
+	remaining := slices.DeleteFunc(slices.Clone(workout.Entries), func(entry store.WorkoutEntry) bool {
+		return int64(entry.ID) == entryID
+	})
+	if len(remaining) == len(workout.Entries) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "entry does not exist"})
+		return
+	}
+
+	workout.Entries = remaining
+	workout.DissolveSmallGroups()
+
+	err = wh.workoutStore.DeleteEntry(workout, entryID, middleware.GetUser(r).ID)
+	if wh.writeEntryError(w, err, "deleteEntry") {
+		return
+	}
+
+	wh.detectPersonalRecords(workout)
+	w.Header().Set("ETag", utils.ETag(workout.Version))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleReorderEntries renumbers the entries of a workout in the order of
+// entry_ids, which lists every entry once.
+func (wh *WorkoutHandler) HandleReorderEntries(w http.ResponseWriter, r *http.Request) {
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	workout := wh.getOwnedWorkout(w, r)
+	if workout == nil || !wh.checkIfMatch(w, r, workout) {
+		return
+	}
+
+	var request reorderEntriesRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		wh.logger.Printf("ERROR: decoding reorder entries: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+
+	positions := make(map[int64]int, len(request.EntryIDs))
+	for i, id := range request.EntryIDs {
+		positions[id] = i + 1
+	}
+	if len(positions) != len(request.EntryIDs) || len(positions) != len(workout.Entries) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "entry_ids must list every entry of the workout once"})
+		return
+	}
+	for i := range workout.Entries {
+		position, ok := positions[int64(workout.Entries[i].ID)]
+		if !ok {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "entry_ids must list every entry of the workout once"})
+			return
+		}
+		workout.Entries[i].OrderIndex = position
+	}
+
+	err = workout.ValidateGroups()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = wh.workoutStore.ReorderEntries(workout, request.EntryIDs, middleware.GetUser(r).ID)
+	if wh.writeEntryError(w, err, "reorderEntries") {
+		return
+	}
+
+	slices.SortFunc(workout.Entries, func(a, b store.WorkoutEntry) int { return a.OrderIndex - b.OrderIndex })
+	workout.ConvertWeights(unit)
+	w.Header().Set("ETag", utils.ETag(workout.Version))
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"workout": workout})
+}
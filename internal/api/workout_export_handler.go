@@ -0,0 +1,71 @@
+package api
+
+import (
+	"fmt"
+	"github.com/helmigandi/go-workout-api/internal/exporter"
+	"github.com/helmigandi/go-workout-api/internal/middleware"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/helmigandi/go-workout-api/internal/utils"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// HandleExportWorkouts streams the caller's workouts performed between the
+// from and to query values as csv, ndjson or json, weights in the units
+// query value. The response is written as the workouts are loaded, so errors
+// after the first workout can only cut the export short.
+func (wh *WorkoutHandler) HandleExportWorkouts(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	format := utils.ReadString(qs, "format", exporter.FormatJSON)
+	if !slices.Contains(exporter.Formats, format) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": exporter.ErrUnknownFormat.Error()})
+		return
+	}
+
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	filter := store.ExportFilter{UserID: middleware.GetUser(r).ID}
+	filter.From, filter.To, err = utils.ReadDateRange(qs)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	// large exports take longer than the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", exporter.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="workouts-%s.%s"`, time.Now().Format("20060102"), format))
+
+	writer, err := exporter.NewWriter(w, format)
+	if err != nil {
+		wh.logger.Printf("ERROR: newWriter: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	written := false
+	err = wh.workoutStore.ExportWorkouts(filter, func(workout *store.Workout) error {
+		written = true
+		workout.ConvertWeights(unit)
+		return writer.WriteWorkout(workout)
+	})
+	if err != nil {
+		wh.logger.Printf("ERROR: exportWorkouts: %v\n", err)
+		if !written {
+			w.Header().Del("Content-Disposition")
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		}
+		return
+	}
+
+	err = writer.Close()
+	if err != nil {
+		wh.logger.Printf("ERROR: exportWorkouts: %v\n", err)
+	}
+}
@@ -4,28 +4,79 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"github.com/go-chi/chi/v5"
+	"github.com/helmigandi/go-workout-api/internal/jsonpatch"
 	"github.com/helmigandi/go-workout-api/internal/middleware"
 	"github.com/helmigandi/go-workout-api/internal/store"
 	"github.com/helmigandi/go-workout-api/internal/utils"
+	"io"
 	"log"
+	"mime"
 	"net/http"
-	"strconv"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
 )
 
 type WorkoutHandler struct {
 	workoutStore store.WorkoutStore
+	recordStore  store.PersonalRecordStore
 	logger       *log.Logger
 }
 
-func NewWorkoutHandler(workoutStore store.WorkoutStore, logger *log.Logger) *WorkoutHandler {
+func NewWorkoutHandler(workoutStore store.WorkoutStore, recordStore store.PersonalRecordStore, logger *log.Logger) *WorkoutHandler {
 	return &WorkoutHandler{
 		workoutStore: workoutStore,
+		recordStore:  recordStore,
 		logger:       logger,
 	}
 }
 
+// detectPersonalRecords returns the records set by the saved workout. The
+// workout is already stored at this point, so a failure is only logged.
+func (wh *WorkoutHandler) detectPersonalRecords(workout *store.Workout) []store.PersonalRecord {
+	records, err := wh.recordStore.DetectPersonalRecords(workout)
+	if err != nil {
+		wh.logger.Printf("ERROR: detectPersonalRecords: %v\n", err)
+		return []store.PersonalRecord{}
+	}
+	return records
+}
+
+func (wh *WorkoutHandler) validateEntries(entries []store.WorkoutEntry) error {
+	for i := range entries {
+		err := entries[i].Validate()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkIfMatch writes 412 Precondition Failed and returns false when the
+// If-Match header of the request names another version of the workout.
+func (wh *WorkoutHandler) checkIfMatch(w http.ResponseWriter, r *http.Request, workout *store.Workout) bool {
+	if utils.IfMatch(r, utils.ETag(workout.Version)) {
+		return true
+	}
+	writeEditConflict(w)
+	return false
+}
+
+func writeEditConflict(w http.ResponseWriter) {
+	utils.WriteJSON(w, http.StatusPreconditionFailed, utils.Envelope{"error": "workout was changed by another request, fetch it again and retry"})
+}
+
+// isClientError reports whether the store rejected a workout because of what
+// the client sent.
+func isClientError(err error) bool {
+	return errors.Is(err, store.ErrUnknownExercise) ||
+		errors.Is(err, store.ErrUnknownWeightUnit) ||
+		errors.Is(err, store.ErrInvalidEntry) ||
+		errors.Is(err, store.ErrInvalidGroup) ||
+		errors.Is(err, store.ErrInvalidTag)
+}
+
 func (wh *WorkoutHandler) HandleGetWorkoutByID(w http.ResponseWriter, r *http.Request) {
 	workoutID, err := utils.ReadIDParam(r)
 	if err != nil {
@@ -34,16 +85,200 @@ func (wh *WorkoutHandler) HandleGetWorkoutByID(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	if !wh.checkVisible(w, workoutID, middleware.GetUser(r)) {
+		return
+	}
+
 	workout, err := wh.workoutStore.GetWorkoutByID(workoutID)
 	if err != nil {
 		wh.logger.Printf("ERROR: getWorkoutByID: %v\n", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
 		return
 	}
+	if workout != nil {
+		workout.ConvertWeights(unit)
+		w.Header().Set("ETag", utils.ETag(workout.Version))
+	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"workout": workout})
 }
 
+func (wh *WorkoutHandler) validateWorkoutFilter(filter *store.WorkoutFilter) error {
+	if filter.Page < 1 || filter.Page > 10_000_000 {
+		return errors.New("page must be between 1 and 10000000")
+	}
+
+	if filter.Limit < 1 || filter.Limit > 100 {
+		return errors.New("limit must be between 1 and 100")
+	}
+
+	if !slices.Contains(store.WorkoutSortSafelist, filter.Sort) {
+		return errors.New("invalid sort value")
+	}
+
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return errors.New("from must not be after to")
+	}
+
+	if filter.MinDuration != nil && *filter.MinDuration < 0 {
+		return errors.New("min_duration must not be negative")
+	}
+
+	if filter.Cursor != "" && !filter.CursorSortable() {
+		return errors.New("cursor pagination requires sorting by date")
+	}
+
+	return nil
+}
+
+func (wh *WorkoutHandler) readWorkoutFilter(r *http.Request) (store.WorkoutFilter, error) {
+	qs := r.URL.Query()
+	filter := store.WorkoutFilter{
+		Title:  utils.ReadString(qs, "title", ""),
+		Sort:   utils.ReadString(qs, "sort", "-date"),
+		Cursor: utils.ReadString(qs, "cursor", ""),
+	}
+
+	var err error
+	filter.Page, err = utils.ReadInt(qs, "page", 1)
+	if err != nil {
+		return filter, err
+	}
+
+	filter.Limit, err = utils.ReadInt(qs, "limit", 20)
+	if err != nil {
+		return filter, err
+	}
+
+	filter.From, filter.To, err = utils.ReadDateRange(qs)
+	if err != nil {
+		return filter, err
+	}
+
+	if qs.Get("tags") != "" {
+		filter.Tags, err = store.NormalizeTags(strings.Split(qs.Get("tags"), ","))
+		if err != nil {
+			return filter, err
+		}
+	}
+
+	switch utils.ReadString(qs, "tag_match", "any") {
+	case "any":
+	case "all":
+		filter.MatchAllTags = true
+	default:
+		return filter, errors.New("tag_match must be any or all")
+	}
+
+	if qs.Has("min_duration") {
+		minDuration, err := utils.ReadInt(qs, "min_duration", 0)
+		if err != nil {
+			return filter, err
+		}
+		filter.MinDuration = &minDuration
+	}
+
+	return filter, nil
+}
+
+func (wh *WorkoutHandler) HandleListWorkouts(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	filter, err := wh.readWorkoutFilter(r)
+	if err == nil {
+		err = wh.validateWorkoutFilter(&filter)
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	filter.UserID = user.ID
+
+	workouts, metadata, err := wh.workoutStore.ListWorkouts(filter)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid cursor"})
+		return
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: listWorkouts: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	for _, workout := range workouts {
+		workout.ConvertWeights(unit)
+	}
+
+	envelope := utils.Envelope{"workouts": workouts, "metadata": metadata, "next_cursor": nil, "prev_cursor": nil}
+	if metadata.NextCursor != "" {
+		envelope["next_cursor"] = metadata.NextCursor
+	}
+	if metadata.PrevCursor != "" {
+		envelope["prev_cursor"] = metadata.PrevCursor
+	}
+
+	utils.WriteJSON(w, http.StatusOK, envelope)
+}
+
+// HandleSearchWorkouts runs a full-text search over the workouts the caller
+// can view, ranked by relevance.
+func (wh *WorkoutHandler) HandleSearchWorkouts(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	filter := store.SearchFilter{
+		UserID: middleware.GetUser(r).ID,
+		Query:  strings.TrimSpace(qs.Get("q")),
+	}
+
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	filter.Page, err = utils.ReadInt(qs, "page", 1)
+	if err == nil {
+		filter.Limit, err = utils.ReadInt(qs, "limit", 20)
+	}
+	switch {
+	case err != nil:
+	case filter.Query == "":
+		err = errors.New("q is required")
+	case len(filter.Query) > 200:
+		err = errors.New("q must not be longer than 200 characters")
+	case filter.Page < 1 || filter.Page > 10_000_000:
+		err = errors.New("page must be between 1 and 10000000")
+	case filter.Limit < 1 || filter.Limit > 100:
+		err = errors.New("limit must be between 1 and 100")
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	results, metadata, err := wh.workoutStore.SearchWorkouts(filter)
+	if err != nil {
+		wh.logger.Printf("ERROR: searchWorkouts: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	for _, result := range results {
+		result.Workout.ConvertWeights(unit)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"results": results, "metadata": metadata})
+}
+
 func (wh *WorkoutHandler) HandleCreateWorkout(w http.ResponseWriter, r *http.Request) {
 	var workout store.Workout
 	err := json.NewDecoder(r.Body).Decode(&workout)
@@ -59,16 +294,40 @@ func (wh *WorkoutHandler) HandleCreateWorkout(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = wh.validateEntries(workout.Entries)
+	if err == nil {
+		err = workout.ValidateGroups()
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
 	workout.UserID = user.ID
+	defaultEntryWeightUnits(workout.Entries, user.WeightUnit)
 
 	createdWorkout, err := wh.workoutStore.CreateWorkout(&workout)
+	if isClientError(err) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		wh.logger.Printf("ERROR: create workout: %v\n", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create workout"})
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"workout": createdWorkout})
+	records := wh.detectPersonalRecords(createdWorkout)
+	createdWorkout.ConvertWeights(unit)
+	convertRecordWeights(records, unit)
+	w.Header().Set("ETag", utils.ETag(createdWorkout.Version))
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"workout": createdWorkout, "personal_records": records})
 }
 
 func (wh *WorkoutHandler) HandleUpdateWorkout(w http.ResponseWriter, r *http.Request) {
@@ -97,7 +356,10 @@ func (wh *WorkoutHandler) HandleUpdateWorkout(w http.ResponseWriter, r *http.Req
 		Description     *string              `json:"description"`
 		DurationMinutes *int                 `json:"duration_minutes"`
 		CaloriesBurned  *int                 `json:"calories_burned"`
+		PerformedAt     *time.Time           `json:"performed_at"`
 		Entries         []store.WorkoutEntry `json:"entries"`
+		Groups          []store.WorkoutGroup `json:"groups"`
+		Tags            []string             `json:"tags"`
 	}
 
 	err = json.NewDecoder(r.Body).Decode(&updateWorkoutRequest)
@@ -119,8 +381,11 @@ func (wh *WorkoutHandler) HandleUpdateWorkout(w http.ResponseWriter, r *http.Req
 	if updateWorkoutRequest.CaloriesBurned != nil {
 		existWorkout.CaloriesBurned = *updateWorkoutRequest.CaloriesBurned
 	}
-	if updateWorkoutRequest.Entries != nil {
-		existWorkout.Entries = updateWorkoutRequest.Entries
+	if updateWorkoutRequest.PerformedAt != nil {
+		existWorkout.PerformedAt = *updateWorkoutRequest.PerformedAt
+	}
+	if updateWorkoutRequest.Tags != nil {
+		existWorkout.Tags = updateWorkoutRequest.Tags
 	}
 
 	user := middleware.GetUser(r)
@@ -129,6 +394,31 @@ func (wh *WorkoutHandler) HandleUpdateWorkout(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if updateWorkoutRequest.Entries != nil {
+		err = wh.validateEntries(updateWorkoutRequest.Entries)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+			return
+		}
+		existWorkout.Entries = updateWorkoutRequest.Entries
+		defaultEntryWeightUnits(existWorkout.Entries, user.WeightUnit)
+	}
+	if updateWorkoutRequest.Entries != nil || updateWorkoutRequest.Groups != nil {
+		// entries and groups are replaced together as labels link them
+		existWorkout.Groups = updateWorkoutRequest.Groups
+		err = existWorkout.ValidateGroups()
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+			return
+		}
+	}
+
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
 	owner, err := wh.workoutStore.GetWorkoutOwner(workoutID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -145,62 +435,244 @@ func (wh *WorkoutHandler) HandleUpdateWorkout(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err = wh.workoutStore.UpdateWorkout(existWorkout)
+	if !wh.checkIfMatch(w, r, existWorkout) {
+		return
+	}
+
+	err = wh.workoutStore.UpdateWorkout(existWorkout, user.ID)
+	if errors.Is(err, store.ErrEditConflict) {
+		writeEditConflict(w)
+		return
+	}
+	if isClientError(err) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		wh.logger.Printf("ERROR: updatingWorkout: %v\n", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"workout": existWorkout})
+	records := wh.detectPersonalRecords(existWorkout)
+	existWorkout.ConvertWeights(unit)
+	convertRecordWeights(records, unit)
+	w.Header().Set("ETag", utils.ETag(existWorkout.Version))
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"workout": existWorkout, "personal_records": records})
 }
 
-func (wh *WorkoutHandler) HandleDeleteWorkout(w http.ResponseWriter, r *http.Request) {
-	paramsWorkoutID := chi.URLParam(r, "id")
-	if paramsWorkoutID == "" {
-		http.NotFound(w, r)
+// resetPatchedSets drops the logged sets of entries whose flat sets, reps,
+// weight or duration were patched while their sets were left alone, so that
+// the sets are expanded from the new values instead of overriding them.
+func resetPatchedSets(before, after []store.WorkoutEntry) {
+	original := make(map[int]store.WorkoutEntry, len(before))
+	for _, entry := range before {
+		original[entry.ID] = entry
+	}
+
+	for i := range after {
+		entry := &after[i]
+		old, ok := original[entry.ID]
+		if !ok || !reflect.DeepEqual(old.SetDetails, entry.SetDetails) {
+			continue
+		}
+		if old.Sets != entry.Sets || !reflect.DeepEqual(old.Reps, entry.Reps) ||
+			!reflect.DeepEqual(old.Weight, entry.Weight) || !reflect.DeepEqual(old.DurationSeconds, entry.DurationSeconds) {
+			entry.SetDetails = nil
+		}
+	}
+}
+
+// HandlePatchWorkout applies a JSON Merge Patch or a JSON Patch, chosen by
+// Content-Type, to the workout as it is returned by GET, with weights in the
+// display unit of the caller.
+func (wh *WorkoutHandler) HandlePatchWorkout(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var applyPatch func(doc, patch []byte) ([]byte, error)
+	switch mediaType {
+	case jsonpatch.MergePatchContentType:
+		applyPatch = jsonpatch.MergePatch
+	case jsonpatch.JSONPatchContentType:
+		applyPatch = jsonpatch.Apply
+	default:
+		w.Header().Set("Accept-Patch", jsonpatch.MergePatchContentType+", "+jsonpatch.JSONPatchContentType)
+		utils.WriteJSON(w, http.StatusUnsupportedMediaType, utils.Envelope{"error": "content type must be " + jsonpatch.MergePatchContentType + " or " + jsonpatch.JSONPatchContentType})
 		return
 	}
 
-	workoutID, err := strconv.ParseInt(paramsWorkoutID, 10, 64)
+	unit, err := readWeightUnit(r)
 	if err != nil {
-		http.NotFound(w, r)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
 		return
 	}
 
-	user := middleware.GetUser(r)
-	if user == nil || user == store.AnonymousUser {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+	existWorkout := wh.getOwnedWorkout(w, r)
+	if existWorkout == nil || !wh.checkIfMatch(w, r, existWorkout) {
 		return
 	}
+	existWorkout.ConvertWeights(unit)
 
-	owner, err := wh.workoutStore.GetWorkoutOwner(workoutID)
+	patch, err := io.ReadAll(r.Body)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout does not exist"})
-			return
-		}
-		wh.logger.Printf("ERROR: getWorkoutOwner: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+
+	doc, err := json.Marshal(existWorkout)
+	if err != nil {
+		wh.logger.Printf("ERROR: encoding workout for patch: %v\n", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
 		return
 	}
 
-	if owner != user.ID {
-		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "Forbidden"})
+	patched, err := applyPatch(doc, patch)
+	switch {
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	case errors.Is(err, jsonpatch.ErrPathNotFound):
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": err.Error()})
+		return
+	case err != nil:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
 		return
 	}
 
-	err = wh.workoutStore.DeleteWorkout(workoutID)
-	if errors.Is(err, sql.ErrNoRows) {
-		http.Error(w, "Workout not found", http.StatusNotFound)
+	var workout store.Workout
+	err = json.Unmarshal(patched, &workout)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "patched workout is invalid: " + err.Error()})
 		return
 	}
 
+	// identity and bookkeeping fields cannot be patched
+	workout.ID = existWorkout.ID
+	workout.UserID = existWorkout.UserID
+	workout.CreatedAt = existWorkout.CreatedAt
+	workout.Version = existWorkout.Version
+	resetPatchedSets(existWorkout.Entries, workout.Entries)
+
+	err = wh.validateEntries(workout.Entries)
+	if err == nil {
+		err = workout.ValidateGroups()
+	}
 	if err != nil {
-		fmt.Println(err)
-		http.Error(w, "Failed to delete workout", http.StatusInternalServerError)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	defaultEntryWeightUnits(workout.Entries, unit)
+
+	err = wh.workoutStore.UpdateWorkout(&workout, middleware.GetUser(r).ID)
+	if errors.Is(err, store.ErrEditConflict) {
+		writeEditConflict(w)
+		return
+	}
+	if isClientError(err) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: patchWorkout: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	records := wh.detectPersonalRecords(&workout)
+	workout.ConvertWeights(unit)
+	convertRecordWeights(records, unit)
+	w.Header().Set("ETag", utils.ETag(workout.Version))
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"workout": workout, "personal_records": records})
+}
+
+// HandleDeleteWorkout deletes the workout, only at the version named by
+// If-Match when the header is sent.
+func (wh *WorkoutHandler) HandleDeleteWorkout(w http.ResponseWriter, r *http.Request) {
+	workout := wh.getOwnedWorkout(w, r)
+	if workout == nil || !wh.checkIfMatch(w, r, workout) {
+		return
+	}
+
+	version := 0
+	if r.Header.Get("If-Match") != "" {
+		version = workout.Version
+	}
+
+	err := wh.workoutStore.DeleteWorkout(int64(workout.ID), version, middleware.GetUser(r).ID)
+	if errors.Is(err, store.ErrEditConflict) {
+		writeEditConflict(w)
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout does not exist"})
+		return
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: deleteWorkout: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// HandleListDeletedWorkouts lists the workouts of the caller that are in the
+// trash and can still be restored.
+func (wh *WorkoutHandler) HandleListDeletedWorkouts(w http.ResponseWriter, r *http.Request) {
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	workouts, err := wh.workoutStore.ListDeletedWorkouts(middleware.GetUser(r).ID)
+	if err != nil {
+		wh.logger.Printf("ERROR: listDeletedWorkouts: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	for _, workout := range workouts {
+		workout.ConvertWeights(unit)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"workouts": workouts})
+}
+
+// HandleRestoreWorkout takes a workout of the caller out of the trash.
+func (wh *WorkoutHandler) HandleRestoreWorkout(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		wh.logger.Printf("ERROR: read id param: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return
+	}
+
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = wh.workoutStore.RestoreWorkout(workoutID, middleware.GetUser(r).ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout is not in the trash"})
+		return
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: restoreWorkout: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	workout, err := wh.workoutStore.GetWorkoutByID(workoutID)
+	if err != nil || workout == nil {
+		wh.logger.Printf("ERROR: getWorkoutByID: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	records := wh.detectPersonalRecords(workout)
+	workout.ConvertWeights(unit)
+	convertRecordWeights(records, unit)
+	w.Header().Set("ETag", utils.ETag(workout.Version))
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"workout": workout, "personal_records": records})
+}
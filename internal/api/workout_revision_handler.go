@@ -0,0 +1,85 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/helmigandi/go-workout-api/internal/middleware"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/helmigandi/go-workout-api/internal/utils"
+	"net/http"
+)
+
+// HandleListRevisions lists the changes made to a workout, newest first,
+// with snapshots of the workout before and after every change.
+func (wh *WorkoutHandler) HandleListRevisions(w http.ResponseWriter, r *http.Request) {
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	workout := wh.getOwnedWorkout(w, r)
+	if workout == nil {
+		return
+	}
+
+	revisions, err := wh.workoutStore.ListRevisions(int64(workout.ID))
+	if err != nil {
+		wh.logger.Printf("ERROR: listRevisions: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	for _, revision := range revisions {
+		if revision.Before != nil {
+			revision.Before.ConvertWeights(unit)
+		}
+		revision.After.ConvertWeights(unit)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"revisions": revisions})
+}
+
+// HandleRollbackWorkout restores the workout to the state it had after a
+// revision. The rollback is recorded as a revision itself, so it can be
+// undone the same way.
+func (wh *WorkoutHandler) HandleRollbackWorkout(w http.ResponseWriter, r *http.Request) {
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	current := wh.getOwnedWorkout(w, r)
+	if current == nil || !wh.checkIfMatch(w, r, current) {
+		return
+	}
+
+	revisionID, err := utils.ReadInt64Param(r, "revisionID")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid revision id"})
+		return
+	}
+
+	workout, err := wh.workoutStore.RollbackWorkout(int64(current.ID), revisionID, current.Version, middleware.GetUser(r).ID)
+	switch {
+	case errors.Is(err, store.ErrEditConflict):
+		writeEditConflict(w)
+		return
+	case errors.Is(err, sql.ErrNoRows):
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "revision does not exist"})
+		return
+	case isClientError(err):
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "cannot roll back to this revision: " + err.Error()})
+		return
+	case err != nil:
+		wh.logger.Printf("ERROR: rollbackWorkout: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	records := wh.detectPersonalRecords(workout)
+	workout.ConvertWeights(unit)
+	convertRecordWeights(records, unit)
+	w.Header().Set("ETag", utils.ETag(workout.Version))
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"workout": workout, "personal_records": records})
+}
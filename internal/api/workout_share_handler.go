@@ -0,0 +1,183 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/helmigandi/go-workout-api/internal/middleware"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/helmigandi/go-workout-api/internal/utils"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type duplicateWorkoutRequest struct {
+	Title       string     `json:"title"`
+	PerformedAt *time.Time `json:"performed_at"`
+}
+
+type shareWorkoutRequest struct {
+	Username string `json:"username"`
+}
+
+// HandleDuplicateWorkout copies a workout of the caller, or one shared with
+// them, into a new workout of the caller. The body is optional.
+func (wh *WorkoutHandler) HandleDuplicateWorkout(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		wh.logger.Printf("ERROR: read id param: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return
+	}
+
+	var request duplicateWorkoutRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil && !errors.Is(err, io.EOF) {
+		wh.logger.Printf("ERROR: decoding duplicate workout: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+	request.Title = strings.TrimSpace(request.Title)
+
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	user := middleware.GetUser(r)
+	if !wh.checkVisible(w, workoutID, user) {
+		return
+	}
+
+	workout, err := wh.workoutStore.DuplicateWorkout(workoutID, user.ID, request.Title, request.PerformedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout does not exist"})
+		return
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: duplicateWorkout: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	records := wh.detectPersonalRecords(workout)
+	workout.ConvertWeights(unit)
+	convertRecordWeights(records, unit)
+	w.Header().Set("ETag", utils.ETag(workout.Version))
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"workout": workout, "personal_records": records})
+}
+
+// checkVisible writes the error response itself and returns false when the
+// workout does not exist or the user may not view it.
+func (wh *WorkoutHandler) checkVisible(w http.ResponseWriter, workoutID int64, user *store.User) bool {
+	owner, err := wh.workoutStore.GetWorkoutOwner(workoutID)
+	if err != nil {
+		wh.logger.Printf("ERROR: getWorkoutOwner: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+	if owner == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout does not exist"})
+		return false
+	}
+	if owner == user.ID {
+		return true
+	}
+
+	visible, err := wh.workoutStore.CanViewWorkout(workoutID, user.ID)
+	if err != nil {
+		wh.logger.Printf("ERROR: canViewWorkout: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+	if !visible {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "Forbidden"})
+		return false
+	}
+
+	return true
+}
+
+func (wh *WorkoutHandler) HandleListWorkoutShares(w http.ResponseWriter, r *http.Request) {
+	workout := wh.getOwnedWorkout(w, r)
+	if workout == nil {
+		return
+	}
+
+	shares, err := wh.workoutStore.ListWorkoutShares(int64(workout.ID))
+	if err != nil {
+		wh.logger.Printf("ERROR: listWorkoutShares: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"shares": shares})
+}
+
+// HandleShareWorkout lets the user named in the body view and duplicate a
+// workout of the caller.
+func (wh *WorkoutHandler) HandleShareWorkout(w http.ResponseWriter, r *http.Request) {
+	workout := wh.getOwnedWorkout(w, r)
+	if workout == nil {
+		return
+	}
+
+	var request shareWorkoutRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		wh.logger.Printf("ERROR: decoding share workout: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+
+	if request.Username == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "username is required"})
+		return
+	}
+	if request.Username == middleware.GetUser(r).Username {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "cannot share a workout with yourself"})
+		return
+	}
+
+	share, err := wh.workoutStore.ShareWorkout(int64(workout.ID), request.Username)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "user does not exist"})
+		return
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: shareWorkout: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"share": share})
+}
+
+func (wh *WorkoutHandler) HandleUnshareWorkout(w http.ResponseWriter, r *http.Request) {
+	workout := wh.getOwnedWorkout(w, r)
+	if workout == nil {
+		return
+	}
+
+	userID, err := utils.ReadInt64Param(r, "userID")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid user id"})
+		return
+	}
+
+	err = wh.workoutStore.UnshareWorkout(int64(workout.ID), int(userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout is not shared with this user"})
+		return
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: unshareWorkout: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
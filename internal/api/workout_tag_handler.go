@@ -0,0 +1,117 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/helmigandi/go-workout-api/internal/middleware"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/helmigandi/go-workout-api/internal/utils"
+	"net/http"
+	"net/url"
+)
+
+type addTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// writeTags answers a tag change with the tags the workout carries now.
+func (wh *WorkoutHandler) writeTags(w http.ResponseWriter, workoutID int64) {
+	workout, err := wh.workoutStore.GetWorkoutByID(workoutID)
+	if err != nil || workout == nil {
+		wh.logger.Printf("ERROR: getWorkoutByID: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	tags := workout.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	w.Header().Set("ETag", utils.ETag(workout.Version))
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"tags": tags})
+}
+
+// HandleAddWorkoutTags tags a workout of the caller, creating tags the caller
+// has not used before.
+func (wh *WorkoutHandler) HandleAddWorkoutTags(w http.ResponseWriter, r *http.Request) {
+	workout := wh.getOwnedWorkout(w, r)
+	if workout == nil || !wh.checkIfMatch(w, r, workout) {
+		return
+	}
+
+	var request addTagsRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		wh.logger.Printf("ERROR: decoding add tags: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request send"})
+		return
+	}
+	if len(request.Tags) == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "tags must list at least one tag"})
+		return
+	}
+
+	err = wh.workoutStore.AddWorkoutTags(workout, request.Tags, middleware.GetUser(r).ID)
+	if errors.Is(err, store.ErrEditConflict) {
+		writeEditConflict(w)
+		return
+	}
+	if errors.Is(err, store.ErrInvalidTag) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: addWorkoutTags: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	wh.writeTags(w, int64(workout.ID))
+}
+
+func (wh *WorkoutHandler) HandleRemoveWorkoutTag(w http.ResponseWriter, r *http.Request) {
+	workout := wh.getOwnedWorkout(w, r)
+	if workout == nil || !wh.checkIfMatch(w, r, workout) {
+		return
+	}
+
+	tag, err := url.PathUnescape(chi.URLParam(r, "tag"))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid tag"})
+		return
+	}
+
+	err = wh.workoutStore.RemoveWorkoutTag(workout, tag, middleware.GetUser(r).ID)
+	switch {
+	case errors.Is(err, store.ErrEditConflict):
+		writeEditConflict(w)
+		return
+	case errors.Is(err, store.ErrInvalidTag):
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	case errors.Is(err, sql.ErrNoRows):
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout does not carry this tag"})
+		return
+	case err != nil:
+		wh.logger.Printf("ERROR: removeWorkoutTag: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	wh.writeTags(w, int64(workout.ID))
+}
+
+// HandleListTags lists the tags of the caller with the number of workouts
+// carrying each.
+func (wh *WorkoutHandler) HandleListTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := wh.workoutStore.ListTags(middleware.GetUser(r).ID)
+	if err != nil {
+		wh.logger.Printf("ERROR: listTags: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"tags": tags})
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/helmigandi/go-workout-api/internal/middleware"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/helmigandi/go-workout-api/internal/track"
+	"github.com/helmigandi/go-workout-api/internal/utils"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxTrackBytes limits the size of an uploaded GPX or TCX file.
+const maxTrackBytes = 25 << 20
+
+// maxTitleBytes limits the title part of a track upload.
+const maxTitleBytes = 1 << 10
+
+// defaultTrackPoints and maxTrackPoints bound the points returned for a
+// track, longer tracks are thinned out.
+const (
+	defaultTrackPoints = 1000
+	maxTrackPoints     = 10000
+)
+
+// HandleImportGPX creates a cardio workout from a GPX file.
+func (wh *WorkoutHandler) HandleImportGPX(w http.ResponseWriter, r *http.Request) {
+	wh.importTrack(w, r, track.ParseGPX)
+}
+
+// HandleImportTCX creates a cardio workout from a TCX file.
+func (wh *WorkoutHandler) HandleImportTCX(w http.ResponseWriter, r *http.Request) {
+	wh.importTrack(w, r, track.ParseTCX)
+}
+
+// importTrack creates a workout from an uploaded track file, which is read
+// with parse as it arrives. The request is multipart/form-data with the file
+// in a "file" part and an optional "title" part that replaces the name of
+// the track. The points of the track are stored with the workout.
+func (wh *WorkoutHandler) importTrack(w http.ResponseWriter, r *http.Request, parse func(io.Reader) (*track.Track, error)) {
+	user := middleware.GetUser(r)
+	r.Body = http.MaxBytesReader(w, r.Body, maxTrackBytes)
+	// track files of long activities take longer to upload than the server's
+	// read timeout
+	_ = http.NewResponseController(w).SetReadDeadline(time.Now().Add(uploadTimeout))
+
+	unit, err := readWeightUnit(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "the request must be multipart/form-data"})
+		return
+	}
+
+	var recorded *track.Track
+	title := ""
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			writeUploadError(w, wh.logger, err)
+			return
+		}
+
+		switch part.FormName() {
+		case "title":
+			value, err := io.ReadAll(io.LimitReader(part, maxTitleBytes))
+			if err != nil {
+				writeUploadError(w, wh.logger, err)
+				return
+			}
+			title = strings.TrimSpace(string(value))
+			if utf8.RuneCountInString(title) > track.MaxTitleLength {
+				utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": fmt.Sprintf("title must not be longer than %d characters", track.MaxTitleLength)})
+				return
+			}
+		case "file":
+			if recorded != nil {
+				utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "only one file can be imported at a time"})
+				return
+			}
+			recorded, err = parse(part)
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writeUploadError(w, wh.logger, maxBytesErr)
+				return
+			}
+			if err != nil {
+				utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": err.Error()})
+				return
+			}
+		}
+	}
+
+	if recorded == nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "file is required"})
+		return
+	}
+
+	workout, err := recorded.Workout(user.ID, title)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	createdWorkout, err := wh.workoutStore.CreateWorkoutWithTrack(workout, recorded.Points)
+	if errors.Is(err, store.ErrInvalidEntry) {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: createWorkoutWithTrack: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create workout"})
+		return
+	}
+
+	records := wh.detectPersonalRecords(createdWorkout)
+	createdWorkout.ConvertWeights(unit)
+	convertRecordWeights(records, unit)
+	w.Header().Set("ETag", utils.ETag(createdWorkout.Version))
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"workout": createdWorkout, "personal_records": records})
+}
+
+// HandleGetTrack returns the recorded track of a workout the caller owns or
+// that is shared with them, empty when the workout was not imported from a
+// track file. Tracks with more points than the max_points query value are
+// thinned out, total_points tells how many the whole track has.
+func (wh *WorkoutHandler) HandleGetTrack(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		wh.logger.Printf("ERROR: read id param: %v\n", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return
+	}
+
+	maxPoints, err := utils.ReadInt(r.URL.Query(), "max_points", defaultTrackPoints)
+	if err == nil && (maxPoints < 2 || maxPoints > maxTrackPoints) {
+		err = fmt.Errorf("max_points must be between 2 and %d", maxTrackPoints)
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	if !wh.checkVisible(w, workoutID, middleware.GetUser(r)) {
+		return
+	}
+
+	points, total, err := wh.workoutStore.ListTrackPoints(workoutID, maxPoints)
+	if err != nil {
+		wh.logger.Printf("ERROR: listTrackPoints: %v\n", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"track_points": points, "total_points": total})
+}
@@ -4,21 +4,31 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/helmigandi/go-workout-api/internal/api"
+	"github.com/helmigandi/go-workout-api/internal/importer"
 	"github.com/helmigandi/go-workout-api/internal/middleware"
 	"github.com/helmigandi/go-workout-api/internal/store"
 	"github.com/helmigandi/go-workout-api/migrations"
 	"log"
 	"net/http"
 	"os"
+	"time"
 )
 
 type Application struct {
-	Logger         *log.Logger
-	WorkoutHandler *api.WorkoutHandler
-	UserHandler    *api.UserHandler
-	TokenHandler   *api.TokenHandler
-	Middleware     middleware.UserMiddleware
-	DB             *sql.DB
+	Logger           *log.Logger
+	WorkoutHandler   *api.WorkoutHandler
+	UserHandler      *api.UserHandler
+	TokenHandler     *api.TokenHandler
+	ExerciseHandler  *api.ExerciseHandler
+	TemplateHandler  *api.TemplateHandler
+	ProgramHandler   *api.ProgramHandler
+	RecordHandler    *api.PersonalRecordHandler
+	AnalyticsHandler *api.AnalyticsHandler
+	ImportHandler    *api.ImportHandler
+	Importer         *importer.Importer
+	Middleware       middleware.UserMiddleware
+	WorkoutStore     store.WorkoutStore
+	DB               *sql.DB
 }
 
 // NewApplication creates a formatted print line across the application.
@@ -39,23 +49,68 @@ func NewApplication() (*Application, error) {
 	workoutStore := store.NewPostgresWorkoutStore(pgDB)
 	userStore := store.NewPostgresUserStore(pgDB)
 	tokenStore := store.NewPostgresTokenStore(pgDB)
+	exerciseStore := store.NewPostgresExerciseStore(pgDB)
+	templateStore := store.NewPostgresTemplateStore(pgDB)
+	programStore := store.NewPostgresProgramStore(pgDB)
+	recordStore := store.NewPostgresPersonalRecordStore(pgDB)
+	analyticsStore := store.NewPostgresAnalyticsStore(pgDB)
+	importJobStore := store.NewPostgresImportJobStore(pgDB)
+
+	// imports run inside the process, so the ones of a previous run are lost
+	_, err = importJobStore.FailUnfinishedImportJobs("the server restarted before the import finished")
+	if err != nil {
+		return nil, err
+	}
 
 	// our handlers will go here
-	workoutHandler := api.NewWorkoutHandler(workoutStore, logger)
+	workoutHandler := api.NewWorkoutHandler(workoutStore, recordStore, logger)
 	userHandler := api.NewUserHandler(userStore, logger)
 	tokenHandler := api.NewTokenHandler(tokenStore, userStore, logger)
+	exerciseHandler := api.NewExerciseHandler(exerciseStore, logger)
+	templateHandler := api.NewTemplateHandler(templateStore, workoutStore, logger)
+	programHandler := api.NewProgramHandler(programStore, workoutStore, logger)
+	recordHandler := api.NewPersonalRecordHandler(recordStore, logger)
+	analyticsHandler := api.NewAnalyticsHandler(analyticsStore, logger)
+	csvImporter := importer.NewImporter(workoutStore, recordStore, importJobStore, logger)
+	importHandler := api.NewImportHandler(csvImporter, importJobStore, logger)
 	middlewareHandler := middleware.UserMiddleware{UserStore: userStore}
 
 	return &Application{
-		Logger:         logger,
-		WorkoutHandler: workoutHandler,
-		UserHandler:    userHandler,
-		TokenHandler:   tokenHandler,
-		Middleware:     middlewareHandler,
-		DB:             pgDB,
+		Logger:           logger,
+		WorkoutHandler:   workoutHandler,
+		UserHandler:      userHandler,
+		TokenHandler:     tokenHandler,
+		ExerciseHandler:  exerciseHandler,
+		TemplateHandler:  templateHandler,
+		ProgramHandler:   programHandler,
+		RecordHandler:    recordHandler,
+		AnalyticsHandler: analyticsHandler,
+		ImportHandler:    importHandler,
+		Importer:         csvImporter,
+		Middleware:       middlewareHandler,
+		WorkoutStore:     workoutStore,
+		DB:               pgDB,
 	}, nil
 }
 
 func (a *Application) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "Status available\n")
 }
+
+// PurgeTrash permanently removes the workouts that have been in the trash for
+// longer than retention, once now and then every interval. It never returns,
+// so run it in its own goroutine.
+func (a *Application) PurgeTrash(retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := a.WorkoutStore.PurgeDeletedWorkouts(time.Now().Add(-retention))
+		if err != nil {
+			a.Logger.Printf("ERROR: purgeDeletedWorkouts: %v\n", err)
+		} else if purged > 0 {
+			a.Logger.Printf("purged %d workouts from the trash", purged)
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,253 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatJSON   = "json"
+)
+
+var Formats = []string{FormatCSV, FormatNDJSON, FormatJSON}
+
+var ErrUnknownFormat = errors.New("format must be csv, ndjson or json")
+
+// Writer writes workouts one at a time in an export format.
+type Writer interface {
+	WriteWorkout(workout *store.Workout) error
+	// Close finishes the export. It does not close the underlying writer.
+	Close() error
+}
+
+// NewWriter returns a Writer for the format that writes to w.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatNDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatJSON:
+		return &jsonWriter{w: bufio.NewWriter(w)}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ContentType returns the media type of the format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	}
+	return "application/json"
+}
+
+// ndjsonWriter writes every workout as a JSON object on a line of its own.
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (nw *ndjsonWriter) WriteWorkout(workout *store.Workout) error {
+	return nw.encoder.Encode(workout)
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nil
+}
+
+// jsonWriter writes a single {"workouts": [...]} document, with one workout
+// on every line.
+type jsonWriter struct {
+	w       *bufio.Writer
+	started bool
+}
+
+func (jw *jsonWriter) WriteWorkout(workout *store.Workout) error {
+	data, err := json.Marshal(workout)
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n"
+	if !jw.started {
+		separator = "{\"workouts\": [\n"
+		jw.started = true
+	}
+	_, err = jw.w.WriteString(separator)
+	if err != nil {
+		return err
+	}
+	_, err = jw.w.Write(data)
+	if err != nil {
+		return err
+	}
+	return jw.w.Flush()
+}
+
+func (jw *jsonWriter) Close() error {
+	if !jw.started {
+		_, err := jw.w.WriteString("{\"workouts\": [")
+		if err != nil {
+			return err
+		}
+	}
+	_, err := jw.w.WriteString("\n]}\n")
+	if err != nil {
+		return err
+	}
+	return jw.w.Flush()
+}
+
+// csvHeader names the columns of CSV exports. Every row is a set, with the
+// fields of its entry and workout repeated. The default mapping of the CSV
+// importer reads the columns of the entries and sets, so exports can be
+// imported again. The ids, order, groups and the description, duration,
+// calories and tags of the workout are not imported.
+var csvHeader = []string{
+	"workout_id", "date", "workout_name", "description", "duration_minutes", "calories_burned", "tags",
+	"entry_id", "exercise_id", "exercise", "entry_type", "group_label", "order_index",
+	"set_order", "weight", "weight_unit", "reps", "duration", "rpe", "rir",
+	"distance", "distance_unit", "pace", "speed", "elevation_gain_meters", "avg_heart_rate", "max_heart_rate",
+	"notes",
+}
+
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (cw *csvWriter) writeHeader() error {
+	if cw.headerWritten {
+		return nil
+	}
+	cw.headerWritten = true
+	return cw.w.Write(csvHeader)
+}
+
+func (cw *csvWriter) WriteWorkout(workout *store.Workout) error {
+	err := cw.writeHeader()
+	if err != nil {
+		return err
+	}
+
+	for _, row := range workoutRows(workout) {
+		err = cw.w.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvWriter) Close() error {
+	err := cw.writeHeader()
+	if err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// workoutRows lays the workout out as CSV rows. Entries without sets and
+// workouts without entries still get a row, with the missing columns empty.
+func workoutRows(workout *store.Workout) [][]string {
+	workoutColumns := []string{
+		strconv.Itoa(workout.ID),
+		// the full precision, so the workout is found as a duplicate when the
+		// export is imported again
+		workout.PerformedAt.UTC().Format(time.RFC3339Nano),
+		workout.Title,
+		workout.Description,
+		strconv.Itoa(workout.DurationMinutes),
+		strconv.Itoa(workout.CaloriesBurned),
+		strings.Join(workout.Tags, ","),
+	}
+
+	var rows [][]string
+	for _, entry := range workout.Entries {
+		entryColumns := []string{
+			strconv.Itoa(entry.ID),
+			formatInt(entry.ExerciseID),
+			entry.ExerciseName,
+			entry.EntryType,
+			entry.GroupLabel,
+			strconv.Itoa(entry.OrderIndex),
+		}
+		cardioColumns := []string{
+			formatFloat(entry.Distance),
+			entry.DistanceUnit,
+			formatFloat(entry.Pace),
+			formatFloat(entry.Speed),
+			formatInt(entry.ElevationGainMeters),
+			formatInt(entry.AvgHeartRate),
+			formatInt(entry.MaxHeartRate),
+		}
+
+		sets := entry.SetDetails
+		if len(sets) == 0 {
+			sets = []store.WorkoutSet{{}}
+		}
+		for _, set := range sets {
+			setOrder := ""
+			if set.IsWarmup {
+				setOrder = "W"
+			} else if set.SetNumber > 0 {
+				setOrder = strconv.Itoa(set.SetNumber)
+			}
+			setColumns := []string{
+				setOrder,
+				formatFloat(set.Weight),
+				entry.WeightUnit,
+				formatInt(set.Reps),
+				formatInt(set.DurationSeconds),
+				formatFloat(set.RPE),
+				formatInt(set.RIR),
+			}
+
+			row := make([]string, 0, len(csvHeader))
+			row = append(row, workoutColumns...)
+			row = append(row, entryColumns...)
+			row = append(row, setColumns...)
+			row = append(row, cardioColumns...)
+			row = append(row, entry.Notes)
+			rows = append(rows, row)
+		}
+	}
+
+	if len(rows) == 0 {
+		row := make([]string, len(csvHeader))
+		copy(row, workoutColumns)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func formatInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func formatFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
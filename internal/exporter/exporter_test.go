@@ -0,0 +1,183 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/helmigandi/go-workout-api/internal/importer"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func testWorkouts() []*store.Workout {
+	return []*store.Workout{
+		{
+			ID:          1,
+			Title:       "Push",
+			PerformedAt: time.Date(2024, 3, 1, 7, 30, 0, 123456000, time.UTC),
+			Tags:        []string{"gym", "upper body"},
+			Entries: []store.WorkoutEntry{
+				{
+					ID:           10,
+					ExerciseName: "Bench Press",
+					EntryType:    store.EntryTypeStrength,
+					WeightUnit:   "lb",
+					Notes:        "felt strong",
+					OrderIndex:   1,
+					SetDetails: []store.WorkoutSet{
+						{SetNumber: 1, Reps: intPtr(12), Weight: floatPtr(40), IsWarmup: true},
+						{SetNumber: 2, Reps: intPtr(5), Weight: floatPtr(82.5), RPE: floatPtr(8.5), RIR: intPtr(1)},
+					},
+				},
+				{
+					ID:           11,
+					ExerciseName: "Plank",
+					EntryType:    store.EntryTypeStrength,
+					OrderIndex:   2,
+					SetDetails:   []store.WorkoutSet{{SetNumber: 1, DurationSeconds: intPtr(60)}},
+				},
+				{
+					ID:                  12,
+					ExerciseName:        "Rowing",
+					EntryType:           store.EntryTypeCardio,
+					OrderIndex:          3,
+					Distance:            floatPtr(5),
+					DistanceUnit:        "km",
+					ElevationGainMeters: intPtr(0),
+					AvgHeartRate:        intPtr(140),
+					MaxHeartRate:        intPtr(165),
+					SetDetails:          []store.WorkoutSet{{SetNumber: 1, DurationSeconds: intPtr(1200)}},
+				},
+			},
+		},
+		{ID: 2, Title: "Rest day", PerformedAt: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+	}
+}
+
+func export(t *testing.T, format string, workouts []*store.Workout) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, format)
+	require.NoError(t, err)
+	for _, workout := range workouts {
+		require.NoError(t, writer.WriteWorkout(workout))
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestCSVExportCanBeImported(t *testing.T) {
+	data := export(t, FormatCSV, testWorkouts())
+
+	parser, err := importer.NewParser(bytes.NewReader(data), importer.DefaultMapping())
+	require.NoError(t, err)
+
+	group, err := parser.Next()
+	require.NoError(t, err)
+
+	workout := group.Workout
+	assert.Equal(t, "Push", workout.Title)
+	assert.True(t, workout.PerformedAt.Equal(testWorkouts()[0].PerformedAt))
+	require.Len(t, workout.Entries, 3)
+
+	bench := workout.Entries[0]
+	assert.Equal(t, "Bench Press", bench.ExerciseName)
+	assert.Equal(t, store.EntryTypeStrength, bench.EntryType)
+	assert.Equal(t, "lb", bench.WeightUnit)
+	assert.Equal(t, "felt strong", bench.Notes)
+	require.Len(t, bench.SetDetails, 2)
+	assert.True(t, bench.SetDetails[0].IsWarmup)
+	assert.Equal(t, 82.5, *bench.SetDetails[1].Weight)
+	assert.Equal(t, 8.5, *bench.SetDetails[1].RPE)
+	assert.Equal(t, 1, *bench.SetDetails[1].RIR)
+	assert.Equal(t, 60, *workout.Entries[1].SetDetails[0].DurationSeconds)
+
+	rowing := workout.Entries[2]
+	assert.Equal(t, store.EntryTypeCardio, rowing.EntryType)
+	assert.Equal(t, 5.0, *rowing.Distance)
+	assert.Equal(t, "km", rowing.DistanceUnit)
+	assert.Equal(t, 0, *rowing.ElevationGainMeters)
+	assert.Equal(t, 140, *rowing.AvgHeartRate)
+	assert.Equal(t, 165, *rowing.MaxHeartRate)
+	assert.Equal(t, 1200, *rowing.SetDetails[0].DurationSeconds)
+	assert.NoError(t, rowing.Validate())
+
+	// the workout without entries has no exercise to import
+	_, err = parser.Next()
+	assert.Error(t, err)
+	assert.Equal(t, []store.ImportRowError{{Row: 6, Error: "exercise is empty"}}, parser.Errors())
+}
+
+func TestJSONExports(t *testing.T) {
+	t.Run("ndjson", func(t *testing.T) {
+		scanner := bufio.NewScanner(bytes.NewReader(export(t, FormatNDJSON, testWorkouts())))
+		titles := []string{}
+		for scanner.Scan() {
+			var workout store.Workout
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &workout))
+			titles = append(titles, workout.Title)
+		}
+		assert.Equal(t, []string{"Push", "Rest day"}, titles)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var archive struct {
+			Workouts []store.Workout `json:"workouts"`
+		}
+		require.NoError(t, json.Unmarshal(export(t, FormatJSON, testWorkouts()), &archive))
+		require.Len(t, archive.Workouts, 2)
+		assert.Equal(t, []string{"gym", "upper body"}, archive.Workouts[0].Tags)
+		assert.Len(t, archive.Workouts[0].Entries[0].SetDetails, 2)
+	})
+
+	t.Run("empty json", func(t *testing.T) {
+		var archive struct {
+			Workouts []store.Workout `json:"workouts"`
+		}
+		require.NoError(t, json.Unmarshal(export(t, FormatJSON, nil), &archive))
+		assert.NotNil(t, archive.Workouts)
+		assert.Empty(t, archive.Workouts)
+	})
+}
+
+// failingWriter fails every write after the first n bytes.
+type failingWriter struct {
+	n int
+}
+
+func (fw *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > fw.n {
+		written := fw.n
+		fw.n = 0
+		return written, errors.New("connection reset")
+	}
+	fw.n -= len(p)
+	return len(p), nil
+}
+
+func TestWritersReportWriteErrors(t *testing.T) {
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			writer, err := NewWriter(&failingWriter{n: 10}, format)
+			require.NoError(t, err)
+			assert.Error(t, writer.WriteWorkout(testWorkouts()[0]))
+		})
+	}
+}
+
+func TestNewWriterRejectsUnknownFormat(t *testing.T) {
+	_, err := NewWriter(&bytes.Buffer{}, "xml")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
@@ -0,0 +1,53 @@
+package fitness
+
+const (
+	FormulaEpley   = "epley"
+	FormulaBrzycki = "brzycki"
+)
+
+var OneRepMaxFormulas = []string{FormulaEpley, FormulaBrzycki}
+
+// EstimateOneRepMax estimates the heaviest single repetition from a set of
+// reps at weight. A single rep is its own one rep max, and Brzycki is only
+// defined below 37 reps.
+func EstimateOneRepMax(weight float64, reps int, formula string) float64 {
+	if reps <= 0 || weight <= 0 {
+		return 0
+	}
+
+	if reps == 1 {
+		return weight
+	}
+
+	switch formula {
+	case FormulaBrzycki:
+		if reps >= 37 {
+			return 0
+		}
+		return weight * 36 / float64(37-reps)
+	default:
+		return weight * (1 + float64(reps)/30)
+	}
+}
+
+const (
+	UnitKilogram = "kg"
+	UnitPound    = "lb"
+)
+
+var WeightUnits = []string{UnitKilogram, UnitPound}
+
+const poundsPerKilogram = 2.20462262185
+
+// ConvertWeight converts weight between kilograms and pounds. An empty unit is
+// taken to be kilograms.
+func ConvertWeight(weight float64, from, to string) float64 {
+	if from == to || (from == "" && to == UnitKilogram) || (from == UnitKilogram && to == "") {
+		return weight
+	}
+
+	if to == UnitPound {
+		return weight * poundsPerKilogram
+	}
+	return weight / poundsPerKilogram
+}
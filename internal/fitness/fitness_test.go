@@ -0,0 +1,37 @@
+package fitness
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEstimateOneRepMax(t *testing.T) {
+	tests := []struct {
+		name    string
+		weight  float64
+		reps    int
+		formula string
+		want    float64
+	}{
+		{name: "single rep", weight: 100, reps: 1, formula: FormulaEpley, want: 100},
+		{name: "epley", weight: 100, reps: 10, formula: FormulaEpley, want: 133.33},
+		{name: "brzycki", weight: 100, reps: 10, formula: FormulaBrzycki, want: 133.33},
+		{name: "brzycki beyond range", weight: 100, reps: 40, formula: FormulaBrzycki, want: 0},
+		{name: "no reps", weight: 100, reps: 0, formula: FormulaEpley, want: 0},
+		{name: "unknown formula falls back to epley", weight: 60, reps: 5, formula: "", want: 70},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, EstimateOneRepMax(tt.weight, tt.reps, tt.formula), 0.01)
+		})
+	}
+}
+
+func TestConvertWeight(t *testing.T) {
+	assert.InDelta(t, 220.46, ConvertWeight(100, UnitKilogram, UnitPound), 0.01)
+	assert.InDelta(t, 102.06, ConvertWeight(225, UnitPound, UnitKilogram), 0.01)
+	assert.InDelta(t, 102.06, ConvertWeight(225, UnitPound, ""), 0.01)
+	assert.Equal(t, 80.0, ConvertWeight(80, "", UnitKilogram))
+	assert.Equal(t, 80.0, ConvertWeight(80, UnitPound, UnitPound))
+}
@@ -0,0 +1,547 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/helmigandi/go-workout-api/internal/fitness"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidMapping = errors.New("invalid column mapping")
+
+// defaultTitle names the workouts of files without a workout name column.
+const defaultTitle = "Imported workout"
+
+// dateLayouts are tried in order for dates without a DateLayout of the
+// mapping. Dates without a zone are taken to be in UTC.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Mapping names the CSV columns that hold each field. Column names are matched
+// case-insensitively, an empty name means the file has no such column. Every
+// row is one set, see Parser for how rows are grouped. The columns of the
+// entry, such as its type and the cardio fields, are read from the first row
+// of the entry.
+type Mapping struct {
+	Date        string `json:"date"`
+	WorkoutName string `json:"workout_name"`
+	Exercise    string `json:"exercise"`
+	// EntryType holds strength or cardio, entries without one are strength.
+	EntryType string `json:"entry_type"`
+	// SetOrder holds the set number, or W for a warm-up set.
+	SetOrder string `json:"set_order"`
+	Weight   string `json:"weight"`
+	Reps     string `json:"reps"`
+	// Duration holds seconds, or a h:mm:ss or m:ss time.
+	Duration string `json:"duration"`
+	RPE      string `json:"rpe"`
+	RIR      string `json:"rir"`
+	// Distance is measured in the unit of the DistanceUnit column, km or mi.
+	Distance      string `json:"distance"`
+	DistanceUnit  string `json:"distance_unit"`
+	ElevationGain string `json:"elevation_gain"`
+	AvgHeartRate  string `json:"avg_heart_rate"`
+	MaxHeartRate  string `json:"max_heart_rate"`
+	Notes         string `json:"notes"`
+	// WeightUnit is the unit of the weight column, kg or lb. Rows with a
+	// unit in the WeightUnitColumn use that one instead.
+	WeightUnit       string `json:"weight_unit"`
+	WeightUnitColumn string `json:"weight_unit_column"`
+	// DateLayout is a Go time layout for the date column.
+	DateLayout string `json:"date_layout"`
+}
+
+// DefaultMapping expects columns named like the ones of CSV exports. Columns
+// of the default mapping that a file lacks are ignored, apart from date and
+// exercise.
+func DefaultMapping() Mapping {
+	return Mapping{
+		Date:             "date",
+		WorkoutName:      "workout_name",
+		Exercise:         "exercise",
+		EntryType:        "entry_type",
+		SetOrder:         "set_order",
+		Weight:           "weight",
+		Reps:             "reps",
+		Duration:         "duration",
+		RPE:              "rpe",
+		RIR:              "rir",
+		Distance:         "distance",
+		DistanceUnit:     "distance_unit",
+		ElevationGain:    "elevation_gain_meters",
+		AvgHeartRate:     "avg_heart_rate",
+		MaxHeartRate:     "max_heart_rate",
+		Notes:            "notes",
+		WeightUnitColumn: "weight_unit",
+	}
+}
+
+func (m Mapping) Validate() error {
+	switch {
+	case m.Date == "":
+		return fmt.Errorf("%w: the date column is required", ErrInvalidMapping)
+	case m.Exercise == "":
+		return fmt.Errorf("%w: the exercise column is required", ErrInvalidMapping)
+	case m.Reps == "" && m.Duration == "":
+		return fmt.Errorf("%w: a reps or duration column is required", ErrInvalidMapping)
+	case m.WeightUnit != "" && !slices.Contains(fitness.WeightUnits, m.WeightUnit):
+		return fmt.Errorf("%w: weight_unit must be kg or lb", ErrInvalidMapping)
+	}
+	return nil
+}
+
+// Group is a workout built from consecutive rows of the file, along with the
+// numbers of those rows.
+type Group struct {
+	Workout *store.Workout
+	Rows    []int
+}
+
+// row is a parsed row of the file. entry holds the fields of the entry
+// besides its exercise, sets and notes.
+type row struct {
+	number      int
+	performedAt time.Time
+	title       string
+	exercise    string
+	setOrder    *int
+	notes       string
+	set         store.WorkoutSet
+	entry       store.WorkoutEntry
+}
+
+// Parser reads workouts from a CSV file one at a time, so files of any size
+// can be imported. Consecutive rows with the same date and workout name make
+// up a workout and consecutive rows of the same exercise within it an entry,
+// which is how workout apps export their logs. Rows of a workout that come
+// after rows of another one make up a group of their own, the Importer adds
+// them to the workout it created for the first group.
+type Parser struct {
+	reader  *csv.Reader
+	mapping Mapping
+	columns map[string]int
+	// line is the number of the last row read, next the first row of the
+	// next group when it has been read already.
+	line   int
+	next   *row
+	rows   int
+	errors []store.ImportRowError
+}
+
+// NewParser reads the header of the file and checks that it has the mapped
+// columns.
+func NewParser(r io.Reader, mapping Mapping) (*Parser, error) {
+	err := mapping.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidMapping)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	defaults := DefaultMapping().fields()
+	columns := map[string]int{}
+	for field, name := range mapping.fields() {
+		if name == "" {
+			continue
+		}
+		position, ok := positions[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			if name == defaults[field] && field != "date" && field != "exercise" {
+				continue
+			}
+			return nil, fmt.Errorf("%w: the file has no %q column", ErrInvalidMapping, name)
+		}
+		columns[field] = position
+	}
+	_, hasReps := columns["reps"]
+	_, hasDuration := columns["duration"]
+	if !hasReps && !hasDuration {
+		return nil, fmt.Errorf("%w: the file has neither a reps nor a duration column", ErrInvalidMapping)
+	}
+
+	return &Parser{reader: reader, mapping: mapping, columns: columns, line: 1}, nil
+}
+
+func (m Mapping) fields() map[string]string {
+	return map[string]string{
+		"date":           m.Date,
+		"workout_name":   m.WorkoutName,
+		"exercise":       m.Exercise,
+		"entry_type":     m.EntryType,
+		"set_order":      m.SetOrder,
+		"weight":         m.Weight,
+		"reps":           m.Reps,
+		"duration":       m.Duration,
+		"rpe":            m.RPE,
+		"rir":            m.RIR,
+		"distance":       m.Distance,
+		"distance_unit":  m.DistanceUnit,
+		"elevation_gain": m.ElevationGain,
+		"avg_heart_rate": m.AvgHeartRate,
+		"max_heart_rate": m.MaxHeartRate,
+		"notes":          m.Notes,
+		"weight_unit":    m.WeightUnitColumn,
+	}
+}
+
+// Rows returns the number of data rows read so far.
+func (p *Parser) Rows() int {
+	return p.rows
+}
+
+// Errors returns the rows that were skipped since the last call and why.
+func (p *Parser) Errors() []store.ImportRowError {
+	rowErrors := p.errors
+	p.errors = nil
+	return rowErrors
+}
+
+// Next returns the next workout of the file, or io.EOF after the last one.
+// Rows that cannot be read are skipped and reported by Errors.
+func (p *Parser) Next() (*Group, error) {
+	var rows []*row
+	for {
+		current := p.next
+		p.next = nil
+		if current == nil {
+			var err error
+			current, err = p.read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if current == nil {
+				continue
+			}
+		}
+
+		if len(rows) > 0 && (!current.performedAt.Equal(rows[0].performedAt) || current.title != rows[0].title) {
+			p.next = current
+			break
+		}
+		rows = append(rows, current)
+	}
+
+	if len(rows) == 0 {
+		return nil, io.EOF
+	}
+	return p.group(rows), nil
+}
+
+// read returns the next row of the file, or nil when the row was skipped.
+func (p *Parser) read() (*row, error) {
+	record, err := p.reader.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		// a malformed row does not keep the reader from reading the next one
+		p.line = parseErr.StartLine
+		p.rows++
+		p.fail(p.line, parseErr.Err.Error())
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.line, _ = p.reader.FieldPos(0)
+	p.rows++
+
+	if isBlank(record) {
+		return nil, nil
+	}
+
+	parsed, err := p.parse(record)
+	if err != nil {
+		p.fail(p.line, err.Error())
+		return nil, nil
+	}
+	return parsed, nil
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Parser) fail(line int, message string) {
+	p.errors = append(p.errors, store.ImportRowError{Row: line, Error: message})
+}
+
+func (p *Parser) value(record []string, field string) string {
+	position, ok := p.columns[field]
+	if !ok || position >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[position])
+}
+
+func (p *Parser) parse(record []string) (*row, error) {
+	parsed := &row{
+		number:   p.line,
+		title:    p.value(record, "workout_name"),
+		exercise: p.value(record, "exercise"),
+		notes:    p.value(record, "notes"),
+	}
+	if parsed.title == "" {
+		parsed.title = defaultTitle
+	}
+	if parsed.exercise == "" {
+		return nil, errors.New("exercise is empty")
+	}
+
+	var err error
+	parsed.performedAt, err = p.parseDate(p.value(record, "date"))
+	if err != nil {
+		return nil, err
+	}
+
+	setOrder := p.value(record, "set_order")
+	if strings.EqualFold(setOrder, "w") {
+		parsed.set.IsWarmup = true
+	} else if setOrder != "" {
+		order, err := strconv.Atoi(setOrder)
+		if err != nil || order < 0 {
+			return nil, fmt.Errorf("invalid set order %q", setOrder)
+		}
+		parsed.setOrder = &order
+	}
+
+	if weight := p.value(record, "weight"); weight != "" {
+		value, err := strconv.ParseFloat(weight, 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid weight %q", weight)
+		}
+		if value > 0 {
+			parsed.set.Weight = &value
+		}
+	}
+
+	if reps := p.value(record, "reps"); reps != "" {
+		value, err := strconv.Atoi(reps)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid reps %q", reps)
+		}
+		if value > 0 {
+			parsed.set.Reps = &value
+		}
+	}
+
+	if duration := p.value(record, "duration"); duration != "" {
+		value, err := parseDuration(duration)
+		if err != nil {
+			return nil, err
+		}
+		if value > 0 {
+			parsed.set.DurationSeconds = &value
+		}
+	}
+
+	switch {
+	case parsed.set.Reps == nil && parsed.set.DurationSeconds == nil:
+		return nil, errors.New("the set has neither reps nor a duration")
+	case parsed.set.Reps != nil && parsed.set.DurationSeconds != nil:
+		return nil, errors.New("the set has both reps and a duration")
+	}
+
+	parsed.set.RPE, err = parseFloat(p.value(record, "rpe"), "rpe")
+	if err != nil {
+		return nil, err
+	}
+	parsed.set.RIR, err = parseInt(p.value(record, "rir"), "rir")
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.parseEntry(record, &parsed.entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+// parseEntry reads the columns of the entry of the row. Their values are
+// checked further when the workout is created.
+func (p *Parser) parseEntry(record []string, entry *store.WorkoutEntry) error {
+	entry.EntryType = strings.ToLower(p.value(record, "entry_type"))
+	if entry.EntryType == "" {
+		entry.EntryType = store.EntryTypeStrength
+	}
+	if !slices.Contains(store.EntryTypes, entry.EntryType) {
+		return fmt.Errorf("invalid entry type %q", entry.EntryType)
+	}
+
+	entry.WeightUnit = strings.ToLower(p.value(record, "weight_unit"))
+	if entry.WeightUnit == "" {
+		entry.WeightUnit = p.mapping.WeightUnit
+	} else if !slices.Contains(fitness.WeightUnits, entry.WeightUnit) {
+		return fmt.Errorf("invalid weight unit %q", entry.WeightUnit)
+	}
+
+	var err error
+	entry.Distance, err = parseFloat(p.value(record, "distance"), "distance")
+	if err != nil {
+		return err
+	}
+	entry.DistanceUnit = strings.ToLower(p.value(record, "distance_unit"))
+	entry.ElevationGainMeters, err = parseInt(p.value(record, "elevation_gain"), "elevation gain")
+	if err != nil {
+		return err
+	}
+	entry.AvgHeartRate, err = parseInt(p.value(record, "avg_heart_rate"), "average heart rate")
+	if err != nil {
+		return err
+	}
+	entry.MaxHeartRate, err = parseInt(p.value(record, "max_heart_rate"), "max heart rate")
+	return err
+}
+
+// parseFloat reads an optional number that must not be negative.
+func parseFloat(value, name string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return &n, nil
+}
+
+// parseInt reads an optional whole number that must not be negative.
+func parseInt(value, name string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return &n, nil
+}
+
+func (p *Parser) parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("date is empty")
+	}
+
+	layouts := dateLayouts
+	if p.mapping.DateLayout != "" {
+		layouts = []string{p.mapping.DateLayout}
+	}
+	for _, layout := range layouts {
+		date, err := time.ParseInLocation(layout, value, time.UTC)
+		if err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// parseDuration reads a number of seconds or a h:mm:ss or m:ss time.
+func parseDuration(value string) (int, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	seconds := 0
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || (i > 0 && n >= 60) {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds, nil
+}
+
+// group builds the workout of the rows, which share its date and title.
+func (p *Parser) group(rows []*row) *Group {
+	workout := &store.Workout{
+		Title:       rows[0].title,
+		PerformedAt: rows[0].performedAt,
+		Entries:     []store.WorkoutEntry{},
+	}
+	group := &Group{Workout: workout, Rows: make([]int, 0, len(rows))}
+
+	var entryRows [][]*row
+	for i, current := range rows {
+		group.Rows = append(group.Rows, current.number)
+		if i == 0 || current.exercise != rows[i-1].exercise {
+			entryRows = append(entryRows, nil)
+		}
+		entryRows[len(entryRows)-1] = append(entryRows[len(entryRows)-1], current)
+	}
+
+	for i, sets := range entryRows {
+		workout.Entries = append(workout.Entries, p.entry(sets, i+1))
+	}
+	return group
+}
+
+func (p *Parser) entry(rows []*row, orderIndex int) store.WorkoutEntry {
+	// warm-ups come first, the other sets in their set order when it is known
+	sorted := slices.Clone(rows)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].set.IsWarmup != sorted[j].set.IsWarmup {
+			return sorted[i].set.IsWarmup
+		}
+		if sorted[i].setOrder == nil || sorted[j].setOrder == nil {
+			return false
+		}
+		return *sorted[i].setOrder < *sorted[j].setOrder
+	})
+
+	entry := rows[0].entry
+	entry.ExerciseName = rows[0].exercise
+	entry.OrderIndex = orderIndex
+	entry.SetDetails = make([]store.WorkoutSet, 0, len(rows))
+
+	var notes []string
+	for _, current := range sorted {
+		entry.SetDetails = append(entry.SetDetails, current.set)
+		if current.notes != "" && !slices.Contains(notes, current.notes) {
+			notes = append(notes, current.notes)
+		}
+	}
+	entry.Notes = strings.Join(notes, "\n")
+
+	return entry
+}
@@ -0,0 +1,153 @@
+package importer
+
+import (
+	"errors"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readGroups(t *testing.T, parser *Parser) ([]*Group, []store.ImportRowError) {
+	t.Helper()
+	var groups []*Group
+	var rowErrors []store.ImportRowError
+	for {
+		group, err := parser.Next()
+		rowErrors = append(rowErrors, parser.Errors()...)
+		if errors.Is(err, io.EOF) {
+			return groups, rowErrors
+		}
+		require.NoError(t, err)
+		groups = append(groups, group)
+	}
+}
+
+func TestParserGroupsRows(t *testing.T) {
+	file := `Date,Workout Name,Exercise Name,Set Order,Weight,Reps,Seconds,Notes
+2024-03-01 07:30:00,Push,Bench Press,1,60,10,0,
+2024-03-01 07:30:00,Push,Bench Press,W,40,12,0,easy
+2024-03-01 07:30:00,Push,Bench Press,2,80,5,0,
+2024-03-01 07:30:00,Push,Plank,1,,,1:30,
+2024-03-03 08:00:00,Pull,Pull Up,1,,8,0,
+`
+	mapping := Mapping{
+		Date:        "Date",
+		WorkoutName: "Workout Name",
+		Exercise:    "Exercise Name",
+		SetOrder:    "set order",
+		Weight:      "Weight",
+		Reps:        "Reps",
+		Duration:    "Seconds",
+		Notes:       "Notes",
+		WeightUnit:  "lb",
+	}
+	parser, err := NewParser(strings.NewReader(file), mapping)
+	require.NoError(t, err)
+
+	groups, rowErrors := readGroups(t, parser)
+	assert.Empty(t, rowErrors)
+	assert.Equal(t, 5, parser.Rows())
+	require.Len(t, groups, 2)
+
+	push := groups[0]
+	assert.Equal(t, []int{2, 3, 4, 5}, push.Rows)
+	assert.Equal(t, "Push", push.Workout.Title)
+	assert.Equal(t, time.Date(2024, 3, 1, 7, 30, 0, 0, time.UTC), push.Workout.PerformedAt)
+	require.Len(t, push.Workout.Entries, 2)
+
+	bench := push.Workout.Entries[0]
+	assert.Equal(t, "Bench Press", bench.ExerciseName)
+	assert.Equal(t, "lb", bench.WeightUnit)
+	assert.Equal(t, "easy", bench.Notes)
+	assert.Equal(t, 1, bench.OrderIndex)
+	require.Len(t, bench.SetDetails, 3)
+	assert.True(t, bench.SetDetails[0].IsWarmup)
+	assert.Equal(t, 60.0, *bench.SetDetails[1].Weight)
+	assert.Equal(t, 80.0, *bench.SetDetails[2].Weight)
+
+	plank := push.Workout.Entries[1]
+	require.Len(t, plank.SetDetails, 1)
+	assert.Nil(t, plank.SetDetails[0].Reps)
+	assert.Equal(t, 90, *plank.SetDetails[0].DurationSeconds)
+	assert.NoError(t, plank.Validate())
+
+	assert.Equal(t, "Pull", groups[1].Workout.Title)
+	assert.Nil(t, groups[1].Workout.Entries[0].SetDetails[0].Weight)
+}
+
+func TestParserReportsRowErrors(t *testing.T) {
+	file := `date,exercise,reps,weight
+2024-03-01,Squat,5,100
+yesterday,Squat,5,100
+2024-03-01,Squat,five,100
+2024-03-01,,5,100
+2024-03-01,Squat,"5,100
+
+2024-03-01,Squat,3,110
+`
+	parser, err := NewParser(strings.NewReader(file), DefaultMapping())
+	require.NoError(t, err)
+
+	groups, rowErrors := readGroups(t, parser)
+	require.Len(t, groups, 1)
+	assert.Equal(t, defaultTitle, groups[0].Workout.Title)
+	require.Len(t, groups[0].Workout.Entries, 1)
+	assert.Len(t, groups[0].Workout.Entries[0].SetDetails, 1)
+
+	rows := []int{}
+	for _, rowError := range rowErrors {
+		rows = append(rows, rowError.Row)
+	}
+	assert.Equal(t, []int{3, 4, 5, 6}, rows)
+}
+
+func TestNewParserChecksColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		mapping Mapping
+	}{
+		{name: "missing date column", header: "when,exercise,reps", mapping: DefaultMapping()},
+		{name: "missing mapped column", header: "date,exercise,reps", mapping: Mapping{Date: "date", Exercise: "exercise", Reps: "reps", Weight: "kg"}},
+		{name: "no reps or duration", header: "date,exercise,weight", mapping: DefaultMapping()},
+		{name: "unknown weight unit", header: "date,exercise,reps", mapping: Mapping{Date: "date", Exercise: "exercise", Reps: "reps", WeightUnit: "st"}},
+		{name: "empty file", header: "", mapping: DefaultMapping()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(strings.NewReader(tt.header), tt.mapping)
+			assert.ErrorIs(t, err, ErrInvalidMapping)
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "45", want: 45},
+		{value: "1:30", want: 90},
+		{value: "1:02:03", want: 3723},
+		{value: "1:75", wantErr: true},
+		{value: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseDuration(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrTooManyJobs = errors.New("too many imports running")
+
+// MaxRowErrors caps the row errors kept on a job, the others are only counted.
+const MaxRowErrors = 100
+
+// progressInterval is the number of groups of rows after which the progress
+// of a running job is saved, whether they were imported, skipped, added to
+// an earlier workout or failed.
+const progressInterval = 25
+
+// MaxJobsPerUser caps the imports a user can have running at the same time.
+const MaxJobsPerUser = 2
+
+type Importer struct {
+	workoutStore store.WorkoutStore
+	recordStore  store.PersonalRecordStore
+	jobStore     store.ImportJobStore
+	logger       *log.Logger
+
+	// ctx is cancelled by Stop, which waits for the running jobs to end.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	jobs    sync.WaitGroup
+	mu      sync.Mutex
+	running map[int]int
+}
+
+func NewImporter(workoutStore store.WorkoutStore, recordStore store.PersonalRecordStore, jobStore store.ImportJobStore, logger *log.Logger) *Importer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Importer{
+		workoutStore: workoutStore,
+		recordStore:  recordStore,
+		jobStore:     jobStore,
+		logger:       logger,
+		ctx:          ctx,
+		cancel:       cancel,
+		running:      map[int]int{},
+	}
+}
+
+// Stop cancels the running jobs and waits for them to save their state. Jobs
+// that are stopped end as failed.
+func (im *Importer) Stop() {
+	im.cancel()
+	im.jobs.Wait()
+}
+
+// reserve takes one of the job slots of the user. It fails with
+// ErrTooManyJobs when the user has none left and with the error of the
+// context once the importer is stopped.
+func (im *Importer) reserve(userID int) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	if err := im.ctx.Err(); err != nil {
+		return err
+	}
+	if im.running[userID] >= MaxJobsPerUser {
+		return ErrTooManyJobs
+	}
+	im.running[userID]++
+	im.jobs.Add(1)
+	return nil
+}
+
+func (im *Importer) release(userID int) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	im.running[userID]--
+	if im.running[userID] == 0 {
+		delete(im.running, userID)
+	}
+	im.jobs.Done()
+}
+
+// StartCSV creates an import job for the user and imports the CSV file at path
+// in the background. The importer owns the file and removes it once it is no
+// longer needed. When the file does not fit the mapping no job is created and
+// the error wraps ErrInvalidMapping. A user can only have MaxJobsPerUser jobs
+// running, past that StartCSV fails with ErrTooManyJobs.
+func (im *Importer) StartCSV(userID int, filename, path string, mapping Mapping) (*store.ImportJob, error) {
+	err := im.reserve(userID)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		os.Remove(path)
+		im.release(userID)
+		return nil, err
+	}
+	cleanup := func() {
+		file.Close()
+		os.Remove(path)
+		im.release(userID)
+	}
+
+	parser, err := NewParser(file, mapping)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	job := &store.ImportJob{UserID: userID, Filename: filename}
+	err = im.jobStore.CreateImportJob(job)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	// the job keeps changing while it runs
+	started := *job
+	go func() {
+		defer cleanup()
+		im.run(im.ctx, job, parser)
+	}()
+
+	return &started, nil
+}
+
+func (im *Importer) run(ctx context.Context, job *store.ImportJob, parser *Parser) {
+	startedAt := time.Now()
+	job.Status = store.ImportRunning
+	job.StartedAt = &startedAt
+	im.save(job)
+
+	err := im.importAll(ctx, job, parser)
+	addErrors(job, parser.Errors())
+	job.RowsProcessed = parser.Rows()
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	switch {
+	case errors.Is(err, context.Canceled):
+		job.Status = store.ImportFailed
+		job.Error = "the import was stopped because the server shut down"
+	case err != nil:
+		im.logger.Printf("ERROR: import job %d: %v\n", job.ID, err)
+		job.Status = store.ImportFailed
+		job.Error = "the import stopped early because of an internal error"
+	default:
+		job.Status = store.ImportCompleted
+	}
+	im.save(job)
+}
+
+// importAll imports the groups of the file until it ends or ctx is done.
+func (im *Importer) importAll(ctx context.Context, job *store.ImportJob, parser *Parser) error {
+	// the workouts of this job by date and title, so rows of a workout that
+	// come later in the file are added to it
+	created := map[workoutKey]int64{}
+	groups := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		group, err := parser.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		addErrors(job, parser.Errors())
+		job.RowsProcessed = parser.Rows()
+
+		err = im.importGroup(job, group, created)
+		if err != nil {
+			return err
+		}
+
+		groups++
+		if groups%progressInterval == 0 {
+			im.save(job)
+		}
+	}
+}
+
+type workoutKey struct {
+	performedAt time.Time
+	title       string
+}
+
+// importGroup creates the workout of the group unless the user already has
+// a workout with its title and date. When the workout was created by this
+// job from earlier rows of the file, the entries of the group are added to it.
+func (im *Importer) importGroup(job *store.ImportJob, group *Group, created map[workoutKey]int64) error {
+	workout := group.Workout
+	workout.UserID = job.UserID
+
+	key := workoutKey{performedAt: workout.PerformedAt.UTC(), title: workout.Title}
+	if id, ok := created[key]; ok {
+		return im.appendGroup(job, group, id)
+	}
+
+	exists, err := im.workoutStore.WorkoutExists(job.UserID, workout.Title, workout.PerformedAt)
+	if err != nil {
+		return err
+	}
+	if exists {
+		job.DuplicatesSkipped++
+		return nil
+	}
+
+	saved, err := im.workoutStore.CreateWorkout(workout)
+	if isRowError(err) {
+		failGroup(job, group, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	job.WorkoutsImported++
+	created[key] = int64(saved.ID)
+
+	im.detectPersonalRecords(saved)
+	return nil
+}
+
+// appendGroup adds the entries of the group to the workout with the id, which
+// this job created.
+func (im *Importer) appendGroup(job *store.ImportJob, group *Group, workoutID int64) error {
+	workout, err := im.workoutStore.GetWorkoutByID(workoutID)
+	if err != nil {
+		return err
+	}
+	if workout == nil {
+		failGroup(job, group, errors.New("the workout of the row was deleted during the import"))
+		return nil
+	}
+
+	last := 0
+	for _, entry := range workout.Entries {
+		last = max(last, entry.OrderIndex)
+	}
+	for _, entry := range group.Workout.Entries {
+		entry.OrderIndex += last
+		workout.Entries = append(workout.Entries, entry)
+	}
+
+	err = im.workoutStore.UpdateWorkout(workout, job.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		failGroup(job, group, errors.New("the workout of the row was deleted during the import"))
+		return nil
+	}
+	if isRowError(err) || errors.Is(err, store.ErrEditConflict) {
+		failGroup(job, group, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	im.detectPersonalRecords(workout)
+	return nil
+}
+
+func (im *Importer) detectPersonalRecords(workout *store.Workout) {
+	_, err := im.recordStore.DetectPersonalRecords(workout)
+	if err != nil {
+		im.logger.Printf("ERROR: detectPersonalRecords: %v\n", err)
+	}
+}
+
+// isRowError reports whether the store rejected a workout because of what
+// the rows hold.
+func isRowError(err error) bool {
+	return errors.Is(err, store.ErrUnknownExercise) || errors.Is(err, store.ErrUnknownWeightUnit) || errors.Is(err, store.ErrInvalidEntry) || errors.Is(err, store.ErrInvalidGroup)
+}
+
+// failGroup reports every row of the group as failed with err.
+func failGroup(job *store.ImportJob, group *Group, err error) {
+	for _, row := range group.Rows {
+		addErrors(job, []store.ImportRowError{{Row: row, Error: err.Error()}})
+	}
+}
+
+func addErrors(job *store.ImportJob, rowErrors []store.ImportRowError) {
+	job.RowsFailed += len(rowErrors)
+	for _, rowError := range rowErrors {
+		if len(job.Errors) >= MaxRowErrors {
+			return
+		}
+		job.Errors = append(job.Errors, rowError)
+	}
+}
+
+func (im *Importer) save(job *store.ImportJob) {
+	err := im.jobStore.UpdateImportJob(job)
+	if err != nil {
+		im.logger.Printf("ERROR: updateImportJob: %v\n", err)
+	}
+}
@@ -0,0 +1,157 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeWorkoutStore keeps workouts in memory, it only has the methods the
+// importer uses.
+type fakeWorkoutStore struct {
+	store.WorkoutStore
+	workouts []*store.Workout
+}
+
+func (s *fakeWorkoutStore) WorkoutExists(userID int, title string, performedAt time.Time) (bool, error) {
+	for _, workout := range s.workouts {
+		if workout.UserID == userID && workout.Title == title && workout.PerformedAt.Equal(performedAt) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *fakeWorkoutStore) CreateWorkout(workout *store.Workout) (*store.Workout, error) {
+	workout.ID = len(s.workouts) + 1
+	s.workouts = append(s.workouts, workout)
+	return workout, nil
+}
+
+func (s *fakeWorkoutStore) GetWorkoutByID(id int64) (*store.Workout, error) {
+	if id < 1 || int(id) > len(s.workouts) {
+		return nil, nil
+	}
+	workout := *s.workouts[id-1]
+	workout.Entries = append([]store.WorkoutEntry{}, workout.Entries...)
+	return &workout, nil
+}
+
+func (s *fakeWorkoutStore) UpdateWorkout(workout *store.Workout, actorID int) error {
+	s.workouts[workout.ID-1] = workout
+	return nil
+}
+
+type fakeRecordStore struct {
+	store.PersonalRecordStore
+}
+
+func (fakeRecordStore) DetectPersonalRecords(workout *store.Workout) ([]store.PersonalRecord, error) {
+	return nil, nil
+}
+
+type fakeJobStore struct {
+	store.ImportJobStore
+	saves int
+}
+
+func (s *fakeJobStore) UpdateImportJob(job *store.ImportJob) error {
+	s.saves++
+	return nil
+}
+
+func TestImporterAddsLaterRowsToTheirWorkout(t *testing.T) {
+	file := `date,workout_name,exercise,reps
+2024-03-01,Push,Bench Press,5
+2024-03-02,Pull,Pull Up,8
+2024-03-01,Push,Dips,10
+2024-03-01,Push,Dips,10
+`
+	parser, err := NewParser(strings.NewReader(file), DefaultMapping())
+	require.NoError(t, err)
+
+	workouts := &fakeWorkoutStore{}
+	im := NewImporter(workouts, fakeRecordStore{}, &fakeJobStore{}, log.New(io.Discard, "", 0))
+	job := &store.ImportJob{UserID: 1}
+	im.run(context.Background(), job, parser)
+
+	assert.Equal(t, store.ImportCompleted, job.Status)
+	assert.Equal(t, 2, job.WorkoutsImported)
+	assert.Equal(t, 0, job.DuplicatesSkipped)
+	assert.Equal(t, 0, job.RowsFailed)
+	require.Len(t, workouts.workouts, 2)
+
+	push := workouts.workouts[0]
+	assert.Equal(t, "Push", push.Title)
+	require.Len(t, push.Entries, 2)
+	assert.Equal(t, "Bench Press", push.Entries[0].ExerciseName)
+	assert.Equal(t, "Dips", push.Entries[1].ExerciseName)
+	assert.Equal(t, 2, push.Entries[1].OrderIndex)
+	assert.Len(t, push.Entries[1].SetDetails, 2)
+}
+
+func TestImporterSavesProgressEveryInterval(t *testing.T) {
+	// a workout split into many blocks only adds rows to the first one
+	var file strings.Builder
+	file.WriteString("date,workout_name,exercise,reps\n")
+	for i := range 2 * progressInterval {
+		fmt.Fprintf(&file, "2024-03-01,Push,Bench Press,5\n2024-03-0%d,Pull %d,Pull Up,8\n", 2+i%2, i)
+	}
+	parser, err := NewParser(strings.NewReader(file.String()), DefaultMapping())
+	require.NoError(t, err)
+
+	jobs := &fakeJobStore{}
+	im := NewImporter(&fakeWorkoutStore{}, fakeRecordStore{}, jobs, log.New(io.Discard, "", 0))
+	job := &store.ImportJob{UserID: 1}
+	im.run(context.Background(), job, parser)
+
+	assert.Equal(t, store.ImportCompleted, job.Status)
+	// the start, every progressInterval of the 100 groups and the end
+	assert.Equal(t, 1+4+1, jobs.saves)
+}
+
+func TestImporterStopsWhenCancelled(t *testing.T) {
+	parser, err := NewParser(strings.NewReader("date,exercise,reps\n2024-03-01,Squat,5\n"), DefaultMapping())
+	require.NoError(t, err)
+
+	workouts := &fakeWorkoutStore{}
+	im := NewImporter(workouts, fakeRecordStore{}, &fakeJobStore{}, log.New(io.Discard, "", 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	job := &store.ImportJob{UserID: 1}
+	im.run(ctx, job, parser)
+
+	assert.Equal(t, store.ImportFailed, job.Status)
+	assert.NotEmpty(t, job.Error)
+	assert.Empty(t, workouts.workouts)
+}
+
+func TestImporterLimitsJobsPerUser(t *testing.T) {
+	im := NewImporter(nil, nil, nil, log.New(io.Discard, "", 0))
+
+	for range MaxJobsPerUser {
+		require.NoError(t, im.reserve(1))
+	}
+	assert.ErrorIs(t, im.reserve(1), ErrTooManyJobs)
+	// other users have their own slots
+	require.NoError(t, im.reserve(2))
+
+	im.release(1)
+	require.NoError(t, im.reserve(1))
+
+	for range MaxJobsPerUser {
+		im.release(1)
+	}
+	im.release(2)
+	assert.Empty(t, im.running)
+
+	im.Stop()
+	assert.ErrorIs(t, im.reserve(1), context.Canceled)
+}
@@ -0,0 +1,130 @@
+package jsonpatch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{name: "replace member", doc: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "add member", doc: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "null removes member", doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "arrays are replaced", doc: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{name: "nested objects merge", doc: `{"e":null,"a":{"b":"c","d":1}}`, patch: `{"a":{"b":null,"x":2}}`, want: `{"e":null,"a":{"d":1,"x":2}}`},
+		{name: "non-object patch replaces", doc: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		{name: "large numbers survive", doc: `{"id":9007199254740993}`, patch: `{"title":"x"}`, want: `{"id":9007199254740993,"title":"x"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+
+	_, err := MergePatch([]byte(`{}`), []byte(`{`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+}
+
+func TestApply(t *testing.T) {
+	doc := `{"title":"push day","description":"upper","entries":[{"id":1,"reps":8},{"id":2,"reps":12}]}`
+
+	tests := []struct {
+		name    string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "replace a nested value",
+			patch: `[{"op":"replace","path":"/entries/1/reps","value":10}]`,
+			want:  `{"title":"push day","description":"upper","entries":[{"id":1,"reps":8},{"id":2,"reps":10}]}`,
+		},
+		{
+			name:  "add to the end of an array and remove a member",
+			patch: `[{"op":"add","path":"/entries/-","value":{"reps":5}},{"op":"remove","path":"/description"}]`,
+			want:  `{"title":"push day","entries":[{"id":1,"reps":8},{"id":2,"reps":12},{"reps":5}]}`,
+		},
+		{
+			name:  "insert into an array",
+			patch: `[{"op":"add","path":"/entries/0","value":{"reps":3}}]`,
+			want:  `{"title":"push day","description":"upper","entries":[{"reps":3},{"id":1,"reps":8},{"id":2,"reps":12}]}`,
+		},
+		{
+			name:  "move and copy",
+			patch: `[{"op":"move","path":"/entries/0","from":"/entries/1"},{"op":"copy","path":"/name","from":"/title"}]`,
+			want:  `{"title":"push day","name":"push day","description":"upper","entries":[{"id":2,"reps":12},{"id":1,"reps":8}]}`,
+		},
+		{
+			name:  "passing test",
+			patch: `[{"op":"test","path":"/entries/0/reps","value":8.0},{"op":"replace","path":"/title","value":"pull day"}]`,
+			want:  `{"title":"pull day","description":"upper","entries":[{"id":1,"reps":8},{"id":2,"reps":12}]}`,
+		},
+		{
+			name:  "null values",
+			patch: `[{"op":"replace","path":"/description","value":null},{"op":"test","path":"/description","value":null},{"op":"add","path":"/notes","value":null}]`,
+			want:  `{"title":"push day","description":null,"notes":null,"entries":[{"id":1,"reps":8},{"id":2,"reps":12}]}`,
+		},
+		{
+			name:    "null does not pass a test of another value",
+			patch:   `[{"op":"test","path":"/title","value":null}]`,
+			wantErr: ErrTestFailed,
+		},
+		{
+			name:    "missing value",
+			patch:   `[{"op":"add","path":"/notes"}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "failing test",
+			patch:   `[{"op":"test","path":"/title","value":"leg day"}]`,
+			wantErr: ErrTestFailed,
+		},
+		{
+			name:    "missing path",
+			patch:   `[{"op":"replace","path":"/notes","value":"x"}]`,
+			wantErr: ErrPathNotFound,
+		},
+		{
+			name:    "index out of range",
+			patch:   `[{"op":"remove","path":"/entries/5"}]`,
+			wantErr: ErrPathNotFound,
+		},
+		{
+			name:    "unknown op",
+			patch:   `[{"op":"merge","path":"/title","value":"x"}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "pointer must start with a slash",
+			patch:   `[{"op":"remove","path":"title"}]`,
+			wantErr: ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(doc), []byte(tt.patch))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestParsePointer(t *testing.T) {
+	tokens, err := parsePointer("/a~1b/m~0n/0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/b", "m~n", "0"}, tokens)
+}
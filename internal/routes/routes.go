@@ -11,10 +11,67 @@ func SetupRoutes(app *app.Application) *chi.Mux {
 	r.Group(func(r chi.Router) {
 		r.Use(app.Middleware.Authenticate)
 
+		r.Patch("/users/me", app.Middleware.RequireUser(app.UserHandler.HandleUpdateCurrentUser))
+
+		r.Get("/workouts", app.Middleware.RequireUser(app.WorkoutHandler.HandleListWorkouts))
 		r.Post("/workouts", app.Middleware.RequireUser(app.WorkoutHandler.HandleCreateWorkout))
+		r.Get("/workouts/search", app.Middleware.RequireUser(app.WorkoutHandler.HandleSearchWorkouts))
+		r.Get("/workouts/trash", app.Middleware.RequireUser(app.WorkoutHandler.HandleListDeletedWorkouts))
 		r.Get("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleGetWorkoutByID))
 		r.Put("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleUpdateWorkout))
+		r.Patch("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandlePatchWorkout))
 		r.Delete("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleDeleteWorkout))
+		r.Post("/workouts/{id}/restore", app.Middleware.RequireUser(app.WorkoutHandler.HandleRestoreWorkout))
+		r.Post("/workouts/{id}/duplicate", app.Middleware.RequireUser(app.WorkoutHandler.HandleDuplicateWorkout))
+		r.Get("/workouts/{id}/shares", app.Middleware.RequireUser(app.WorkoutHandler.HandleListWorkoutShares))
+		r.Post("/workouts/{id}/shares", app.Middleware.RequireUser(app.WorkoutHandler.HandleShareWorkout))
+		r.Delete("/workouts/{id}/shares/{userID}", app.Middleware.RequireUser(app.WorkoutHandler.HandleUnshareWorkout))
+		r.Post("/workouts/{id}/tags", app.Middleware.RequireUser(app.WorkoutHandler.HandleAddWorkoutTags))
+		r.Delete("/workouts/{id}/tags/{tag}", app.Middleware.RequireUser(app.WorkoutHandler.HandleRemoveWorkoutTag))
+		r.Get("/workouts/{id}/track", app.Middleware.RequireUser(app.WorkoutHandler.HandleGetTrack))
+		r.Get("/workouts/{id}/revisions", app.Middleware.RequireUser(app.WorkoutHandler.HandleListRevisions))
+		r.Post("/workouts/{id}/revisions/{revisionID}/rollback", app.Middleware.RequireUser(app.WorkoutHandler.HandleRollbackWorkout))
+		r.Post("/workouts/{id}/entries", app.Middleware.RequireUser(app.WorkoutHandler.HandleCreateEntry))
+		r.Put("/workouts/{id}/entries/order", app.Middleware.RequireUser(app.WorkoutHandler.HandleReorderEntries))
+		r.Patch("/workouts/{id}/entries/{entryID}", app.Middleware.RequireUser(app.WorkoutHandler.HandleUpdateEntry))
+		r.Delete("/workouts/{id}/entries/{entryID}", app.Middleware.RequireUser(app.WorkoutHandler.HandleDeleteEntry))
+
+		r.Get("/tags", app.Middleware.RequireUser(app.WorkoutHandler.HandleListTags))
+
+		r.Get("/exports/workouts", app.Middleware.RequireUser(app.WorkoutHandler.HandleExportWorkouts))
+
+		r.Get("/exercises", app.Middleware.RequireUser(app.ExerciseHandler.HandleListExercises))
+		r.Post("/exercises", app.Middleware.RequireUser(app.ExerciseHandler.HandleCreateExercise))
+		r.Get("/exercises/{id}", app.Middleware.RequireUser(app.ExerciseHandler.HandleGetExerciseByID))
+		r.Put("/exercises/{id}", app.Middleware.RequireUser(app.ExerciseHandler.HandleUpdateExercise))
+		r.Delete("/exercises/{id}", app.Middleware.RequireUser(app.ExerciseHandler.HandleDeleteExercise))
+
+		r.Get("/templates", app.Middleware.RequireUser(app.TemplateHandler.HandleListTemplates))
+		r.Post("/templates", app.Middleware.RequireUser(app.TemplateHandler.HandleCreateTemplate))
+		r.Get("/templates/{id}", app.Middleware.RequireUser(app.TemplateHandler.HandleGetTemplateByID))
+		r.Put("/templates/{id}", app.Middleware.RequireUser(app.TemplateHandler.HandleUpdateTemplate))
+		r.Delete("/templates/{id}", app.Middleware.RequireUser(app.TemplateHandler.HandleDeleteTemplate))
+		r.Post("/templates/{id}/start", app.Middleware.RequireUser(app.TemplateHandler.HandleStartTemplate))
+
+		r.Get("/programs", app.Middleware.RequireUser(app.ProgramHandler.HandleListPrograms))
+		r.Post("/programs", app.Middleware.RequireUser(app.ProgramHandler.HandleCreateProgram))
+		r.Get("/programs/{id}", app.Middleware.RequireUser(app.ProgramHandler.HandleGetProgramByID))
+		r.Delete("/programs/{id}", app.Middleware.RequireUser(app.ProgramHandler.HandleDeleteProgram))
+		r.Post("/programs/{id}/enroll", app.Middleware.RequireUser(app.ProgramHandler.HandleEnroll))
+		r.Get("/enrollments", app.Middleware.RequireUser(app.ProgramHandler.HandleListEnrollments))
+		r.Get("/enrollments/{id}/today", app.Middleware.RequireUser(app.ProgramHandler.HandleGetTodaySession))
+		r.Get("/enrollments/{id}/progress", app.Middleware.RequireUser(app.ProgramHandler.HandleGetProgress))
+		r.Post("/enrollments/{id}/days/{dayID}/complete", app.Middleware.RequireUser(app.ProgramHandler.HandleCompleteSession))
+
+		r.Get("/personal-records", app.Middleware.RequireUser(app.RecordHandler.HandleListPersonalRecords))
+
+		r.Get("/analytics/summary", app.Middleware.RequireUser(app.AnalyticsHandler.HandleGetSummary))
+		r.Get("/analytics/exercises/{exercise}", app.Middleware.RequireUser(app.AnalyticsHandler.HandleGetExerciseProgress))
+
+		r.Post("/imports/csv", app.Middleware.RequireUser(app.ImportHandler.HandleImportCSV))
+		r.Post("/imports/gpx", app.Middleware.RequireUser(app.WorkoutHandler.HandleImportGPX))
+		r.Post("/imports/tcx", app.Middleware.RequireUser(app.WorkoutHandler.HandleImportTCX))
+		r.Get("/imports/{id}", app.Middleware.RequireUser(app.ImportHandler.HandleGetImportJob))
 	})
 
 	r.Get("/health", app.HealthCheck)
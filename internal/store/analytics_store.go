@@ -0,0 +1,342 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/helmigandi/go-workout-api/internal/fitness"
+	"strconv"
+	"time"
+)
+
+var AnalyticsBuckets = []string{"day", "week", "month"}
+
+var SummaryPeriods = []string{"week", "month"}
+
+// oneRepMaxExpressions estimate a one rep max in SQL from the reps r and
+// weight w of a set, mirroring fitness.EstimateOneRepMax.
+var oneRepMaxExpressions = map[string]string{
+	fitness.FormulaEpley:   `CASE WHEN r = 1 THEN w WHEN r > 1 THEN w * (1 + r / 30.0) END`,
+	fitness.FormulaBrzycki: `CASE WHEN r = 1 THEN w WHEN r BETWEEN 2 AND 36 THEN w * 36 / (37 - r) END`,
+}
+
+type ExerciseProgressQuery struct {
+	UserID int
+	// Exercise is either a catalog id or a free text exercise name.
+	Exercise string
+	Bucket   string
+	Formula  string
+	From     *time.Time
+	To       *time.Time
+	// Location is the timezone the buckets start in, UTC when nil.
+	Location *time.Location
+}
+
+type ProgressPoint struct {
+	Bucket             time.Time `json:"bucket"`
+	Workouts           int       `json:"workouts"`
+	Volume             float64   `json:"volume"`
+	MaxWeight          *float64  `json:"max_weight"`
+	EstimatedOneRepMax *float64  `json:"estimated_1rm"`
+}
+
+type SummaryQuery struct {
+	UserID int
+	Period string
+	// Date is any moment inside the period to summarise.
+	Date     time.Time
+	Location *time.Location
+	// TopExercises limits the number of most-trained exercises.
+	TopExercises int
+}
+
+type PeriodTotals struct {
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	Workouts        int       `json:"workouts"`
+	DurationMinutes int       `json:"duration_minutes"`
+	CaloriesBurned  int       `json:"calories_burned"`
+	Volume          float64   `json:"volume"`
+}
+
+type Delta struct {
+	Change float64 `json:"change"`
+	// Percent is nil when the previous period had nothing to compare with.
+	Percent *float64 `json:"percent"`
+}
+
+func newDelta(current, previous float64) Delta {
+	delta := Delta{Change: roundTo(current-previous, 2)}
+	if previous != 0 {
+		percent := roundTo((current-previous)/previous*100, 2)
+		delta.Percent = &percent
+	}
+	return delta
+}
+
+type SummaryDeltas struct {
+	Workouts        Delta `json:"workouts"`
+	DurationMinutes Delta `json:"duration_minutes"`
+	CaloriesBurned  Delta `json:"calories_burned"`
+	Volume          Delta `json:"volume"`
+}
+
+type ExerciseSummary struct {
+	ExerciseName string  `json:"exercise_name"`
+	Workouts     int     `json:"workouts"`
+	Sets         int     `json:"sets"`
+	Volume       float64 `json:"volume"`
+}
+
+type TrainingSummary struct {
+	Period       string            `json:"period"`
+	Timezone     string            `json:"timezone"`
+	Current      PeriodTotals      `json:"current"`
+	Previous     PeriodTotals      `json:"previous"`
+	Deltas       SummaryDeltas     `json:"deltas"`
+	TopExercises []ExerciseSummary `json:"top_exercises"`
+}
+
+// PeriodBounds returns the start of the week (starting Monday) or month that
+// contains date in loc, the start of the following one and the start of the
+// preceding one.
+func PeriodBounds(period string, date time.Time, loc *time.Location) (start, end, previous time.Time) {
+	date = date.In(loc)
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+
+	if period == "month" {
+		start = day.AddDate(0, 0, 1-day.Day())
+		return start, start.AddDate(0, 1, 0), start.AddDate(0, -1, 0)
+	}
+
+	offset := (int(day.Weekday()) + 6) % 7
+	start = day.AddDate(0, 0, -offset)
+	return start, start.AddDate(0, 0, 7), start.AddDate(0, 0, -7)
+}
+
+type PostgresAnalyticsStore struct {
+	db *sql.DB
+}
+
+func NewPostgresAnalyticsStore(db *sql.DB) *PostgresAnalyticsStore {
+	return &PostgresAnalyticsStore{
+		db: db,
+	}
+}
+
+type AnalyticsStore interface {
+	GetExerciseProgress(query ExerciseProgressQuery) ([]ProgressPoint, error)
+	GetTrainingSummary(query SummaryQuery) (*TrainingSummary, error)
+}
+
+// resolveExercise turns an exercise id or name into both forms, so entries
+// match whether they were linked to the catalog or logged as free text.
+func (pg *PostgresAnalyticsStore) resolveExercise(userID int, exercise string) (*int64, string, error) {
+	visible := `(user_id IS NULL OR user_id = $2)`
+
+	if id, err := strconv.ParseInt(exercise, 10, 64); err == nil {
+		var name string
+		err = pg.db.QueryRow(`SELECT name FROM exercises WHERE id = $1 AND `+visible, id, userID).Scan(&name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", ErrUnknownExercise
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		return &id, name, nil
+	}
+
+	var id int64
+	query := `SELECT id FROM exercises WHERE lower(name) = lower($1) AND ` + visible + ` ORDER BY user_id NULLS LAST LIMIT 1`
+	err := pg.db.QueryRow(query, exercise, userID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, exercise, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &id, exercise, nil
+}
+
+// GetExerciseProgress buckets the caller's working sets of one exercise by
+// performed_at, with the buckets starting at midnight in q.Location. Entries
+// logged before per-set tracking fall back to their flat sets, reps and
+// weight.
+func (pg *PostgresAnalyticsStore) GetExerciseProgress(q ExerciseProgressQuery) ([]ProgressPoint, error) {
+	exerciseID, exerciseName, err := pg.resolveExercise(q.UserID, q.Exercise)
+	if err != nil {
+		return nil, err
+	}
+
+	oneRepMax, ok := oneRepMaxExpressions[q.Formula]
+	if !ok {
+		oneRepMax = oneRepMaxExpressions[fitness.FormulaEpley]
+	}
+	location := q.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	query := fmt.Sprintf(`
+		SELECT bucket, count(DISTINCT workout_id), COALESCE(sum(volume), 0), max(w), max(%s)
+		FROM (
+			SELECT date_trunc($2, wo.performed_at AT TIME ZONE $7) AT TIME ZONE $7 AS bucket,
+				wo.id AS workout_id,
+				CASE WHEN s.id IS NULL THEN e.sets * e.reps * e.weight ELSE s.reps * s.weight END AS volume,
+				COALESCE(s.reps, e.reps) AS r,
+				COALESCE(s.weight, e.weight) AS w
+			FROM workout_entries e
+			JOIN workouts wo ON wo.id = e.workout_id
+			LEFT JOIN workout_sets s ON s.workout_entry_id = e.id AND NOT s.is_warmup
+			WHERE wo.user_id = $1 AND wo.deleted_at IS NULL
+			AND (e.exercise_id = $3 OR lower(e.exercise_name) = lower($4))
+			AND ($5::timestamptz IS NULL OR wo.performed_at >= $5)
+			AND ($6::timestamptz IS NULL OR wo.performed_at < $6)
+		) sets
+		GROUP BY bucket
+		ORDER BY bucket
+	`, oneRepMax)
+
+	rows, err := pg.db.Query(query, q.UserID, q.Bucket, exerciseID, exerciseName, q.From, q.To, location.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []ProgressPoint{}
+	for rows.Next() {
+		var point ProgressPoint
+		err = rows.Scan(
+			&point.Bucket,
+			&point.Workouts,
+			&point.Volume,
+			&point.MaxWeight,
+			&point.EstimatedOneRepMax,
+		)
+		if err != nil {
+			return nil, err
+		}
+		point.Bucket = point.Bucket.In(location)
+		if point.EstimatedOneRepMax != nil {
+			rounded := roundTo(*point.EstimatedOneRepMax, 2)
+			point.EstimatedOneRepMax = &rounded
+		}
+		points = append(points, point)
+	}
+
+	return points, rows.Err()
+}
+
+// GetTrainingSummary totals the period containing q.Date and the one before
+// it, with the period boundaries taken in the caller's timezone.
+func (pg *PostgresAnalyticsStore) GetTrainingSummary(q SummaryQuery) (*TrainingSummary, error) {
+	start, end, previous := PeriodBounds(q.Period, q.Date, q.Location)
+	summary := &TrainingSummary{
+		Period:   q.Period,
+		Timezone: q.Location.String(),
+		Current:  PeriodTotals{Start: start, End: end},
+		Previous: PeriodTotals{Start: previous, End: start},
+	}
+
+	query := `
+		WITH periods (name, starts_at, ends_at) AS (
+			VALUES ('current', $2::timestamptz, $3::timestamptz),
+				('previous', $4::timestamptz, $2::timestamptz)
+		),
+		totals AS (
+			SELECT p.name, count(w.id) AS workouts,
+				COALESCE(sum(w.duration_minutes), 0) AS duration_minutes,
+				COALESCE(sum(w.calories_burned), 0) AS calories_burned
+			FROM periods p
+			LEFT JOIN workouts w ON w.user_id = $1 AND w.deleted_at IS NULL
+				AND w.performed_at >= p.starts_at AND w.performed_at < p.ends_at
+			GROUP BY p.name
+		),
+		volumes AS (
+			SELECT p.name,
+				COALESCE(sum(CASE WHEN s.id IS NULL THEN e.sets * e.reps * e.weight ELSE s.reps * s.weight END), 0) AS volume
+			FROM periods p
+			LEFT JOIN workouts w ON w.user_id = $1 AND w.deleted_at IS NULL
+				AND w.performed_at >= p.starts_at AND w.performed_at < p.ends_at
+			LEFT JOIN workout_entries e ON e.workout_id = w.id
+			LEFT JOIN workout_sets s ON s.workout_entry_id = e.id AND NOT s.is_warmup
+			GROUP BY p.name
+		)
+		SELECT t.name, t.workouts, t.duration_minutes, t.calories_burned, v.volume
+		FROM totals t
+		JOIN volumes v ON v.name = t.name
+	`
+	rows, err := pg.db.Query(query, q.UserID, start, end, previous)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var totals PeriodTotals
+		err = rows.Scan(&name, &totals.Workouts, &totals.DurationMinutes, &totals.CaloriesBurned, &totals.Volume)
+		if err != nil {
+			return nil, err
+		}
+
+		period := &summary.Current
+		if name == "previous" {
+			period = &summary.Previous
+		}
+		totals.Start, totals.End = period.Start, period.End
+		*period = totals
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	summary.Deltas = SummaryDeltas{
+		Workouts:        newDelta(float64(summary.Current.Workouts), float64(summary.Previous.Workouts)),
+		DurationMinutes: newDelta(float64(summary.Current.DurationMinutes), float64(summary.Previous.DurationMinutes)),
+		CaloriesBurned:  newDelta(float64(summary.Current.CaloriesBurned), float64(summary.Previous.CaloriesBurned)),
+		Volume:          newDelta(summary.Current.Volume, summary.Previous.Volume),
+	}
+
+	summary.TopExercises, err = pg.topExercises(q.UserID, start, end, q.TopExercises)
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// topExercises ranks the exercises trained in [start, end) by working sets.
+func (pg *PostgresAnalyticsStore) topExercises(userID int, start, end time.Time, limit int) ([]ExerciseSummary, error) {
+	query := `
+		SELECT min(e.exercise_name),
+			count(DISTINCT w.id),
+			COALESCE(sum(CASE WHEN s.id IS NULL THEN e.sets ELSE 1 END), 0),
+			COALESCE(sum(CASE WHEN s.id IS NULL THEN e.sets * e.reps * e.weight ELSE s.reps * s.weight END), 0)
+		FROM workouts w
+		JOIN workout_entries e ON e.workout_id = w.id
+		LEFT JOIN workout_sets s ON s.workout_entry_id = e.id AND NOT s.is_warmup
+		WHERE w.user_id = $1 AND w.deleted_at IS NULL AND w.performed_at >= $2 AND w.performed_at < $3
+		GROUP BY lower(e.exercise_name)
+		ORDER BY 3 DESC, 4 DESC, 1
+		LIMIT $4
+	`
+	rows, err := pg.db.Query(query, userID, start, end, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exercises := []ExerciseSummary{}
+	for rows.Next() {
+		var exercise ExerciseSummary
+		err = rows.Scan(&exercise.ExerciseName, &exercise.Workouts, &exercise.Sets, &exercise.Volume)
+		if err != nil {
+			return nil, err
+		}
+		exercises = append(exercises, exercise)
+	}
+
+	return exercises, rows.Err()
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestPeriodBounds(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	require.NoError(t, err)
+
+	// Sunday evening in UTC is already Monday morning in Jakarta
+	date := time.Date(2025, 3, 16, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		period       string
+		loc          *time.Location
+		wantStart    time.Time
+		wantEnd      time.Time
+		wantPrevious time.Time
+	}{
+		{
+			name:         "week in utc",
+			period:       "week",
+			loc:          time.UTC,
+			wantStart:    time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC),
+			wantEnd:      time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC),
+			wantPrevious: time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "week in the caller's timezone",
+			period:       "week",
+			loc:          jakarta,
+			wantStart:    time.Date(2025, 3, 17, 0, 0, 0, 0, jakarta),
+			wantEnd:      time.Date(2025, 3, 24, 0, 0, 0, 0, jakarta),
+			wantPrevious: time.Date(2025, 3, 10, 0, 0, 0, 0, jakarta),
+		},
+		{
+			name:         "month",
+			period:       "month",
+			loc:          time.UTC,
+			wantStart:    time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			wantEnd:      time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+			wantPrevious: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, previous := PeriodBounds(tt.period, date, tt.loc)
+			assert.True(t, tt.wantStart.Equal(start), "start %v", start)
+			assert.True(t, tt.wantEnd.Equal(end), "end %v", end)
+			assert.True(t, tt.wantPrevious.Equal(previous), "previous %v", previous)
+		})
+	}
+
+	delta := newDelta(120, 100)
+	assert.Equal(t, 20.0, delta.Change)
+	require.NotNil(t, delta.Percent)
+	assert.Equal(t, 20.0, *delta.Percent)
+	assert.Nil(t, newDelta(5, 0).Percent)
+}
+
+func TestGetExerciseProgress(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	workouts := NewPostgresWorkoutStore(db)
+	analytics := NewPostgresAnalyticsStore(db)
+	user := createTestUser(t, db, "progress_lifter")
+	other := createTestUser(t, db, "progress_other")
+
+	monday := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	logged := []struct {
+		userID      int
+		performedAt time.Time
+		sets        []WorkoutSet
+	}{
+		{user.ID, monday, []WorkoutSet{
+			{SetNumber: 1, Reps: createIntPtr(10), Weight: createFloatPtr(20), IsWarmup: true},
+			{SetNumber: 2, Reps: createIntPtr(5), Weight: createFloatPtr(100)},
+		}},
+		{user.ID, monday.AddDate(0, 0, 2), []WorkoutSet{
+			{SetNumber: 1, Reps: createIntPtr(1), Weight: createFloatPtr(110)},
+		}},
+		{user.ID, monday.AddDate(0, 0, 7), []WorkoutSet{
+			{SetNumber: 1, Reps: createIntPtr(3), Weight: createFloatPtr(105)},
+		}},
+		{other.ID, monday, []WorkoutSet{
+			{SetNumber: 1, Reps: createIntPtr(1), Weight: createFloatPtr(200)},
+		}},
+	}
+	for _, workout := range logged {
+		_, err := workouts.CreateWorkout(&Workout{
+			UserID:      workout.userID,
+			Title:       "squat day",
+			PerformedAt: workout.performedAt,
+			Entries:     []WorkoutEntry{{ExerciseName: "Back Squat", SetDetails: workout.sets, OrderIndex: 1}},
+		})
+		require.NoError(t, err)
+	}
+
+	points, err := analytics.GetExerciseProgress(ExerciseProgressQuery{
+		UserID:   user.ID,
+		Exercise: "back squat",
+		Bucket:   "week",
+	})
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+
+	// warm-up sets and the other user's workouts are left out
+	assert.Equal(t, monday.Truncate(24*time.Hour), points[0].Bucket.UTC())
+	assert.Equal(t, 2, points[0].Workouts)
+	assert.Equal(t, 610.0, points[0].Volume)
+	assert.Equal(t, 110.0, *points[0].MaxWeight)
+	assert.Equal(t, 116.67, *points[0].EstimatedOneRepMax)
+	assert.Equal(t, 315.0, points[1].Volume)
+
+	to := monday.AddDate(0, 0, 7)
+	points, err = analytics.GetExerciseProgress(ExerciseProgressQuery{
+		UserID:   user.ID,
+		Exercise: "back squat",
+		Bucket:   "day",
+		To:       &to,
+	})
+	require.NoError(t, err)
+	assert.Len(t, points, 2)
+
+	_, err = analytics.GetExerciseProgress(ExerciseProgressQuery{UserID: user.ID, Exercise: "-1", Bucket: "week"})
+	assert.ErrorIs(t, err, ErrUnknownExercise)
+
+	// a workout late in the evening in New York is on the next day in UTC
+	night := createTestUser(t, db, "progress_night")
+	_, err = workouts.CreateWorkout(&Workout{
+		UserID:      night.ID,
+		Title:       "late squats",
+		PerformedAt: time.Date(2025, 3, 4, 2, 0, 0, 0, time.UTC),
+		Entries:     []WorkoutEntry{{ExerciseName: "Back Squat", Sets: 1, Reps: createIntPtr(5), Weight: createFloatPtr(80), OrderIndex: 1}},
+	})
+	require.NoError(t, err)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	points, err = analytics.GetExerciseProgress(ExerciseProgressQuery{UserID: night.ID, Exercise: "back squat", Bucket: "day", Location: newYork})
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.True(t, time.Date(2025, 3, 3, 0, 0, 0, 0, newYork).Equal(points[0].Bucket))
+
+	points, err = analytics.GetExerciseProgress(ExerciseProgressQuery{UserID: night.ID, Exercise: "back squat", Bucket: "day"})
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.True(t, time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC).Equal(points[0].Bucket))
+}
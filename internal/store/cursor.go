@@ -0,0 +1,89 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// workoutCursor is the keyset position of a workout in a listing. It is
+// handed to clients as an opaque string.
+type workoutCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int       `json:"i"`
+	// Backward marks a cursor that pages towards the start of the listing.
+	Backward bool `json:"b,omitempty"`
+	// Direction and Filter tie the cursor to the listing it was issued for,
+	// as the position means nothing in another order or selection.
+	Direction string `json:"d"`
+	Filter    string `json:"f"`
+}
+
+// matches reports whether the cursor was issued for a listing of the filter.
+func (c workoutCursor) matches(filter WorkoutFilter) bool {
+	return c.Direction == filter.sortDirection() && c.Filter == cursorFilterHash(filter)
+}
+
+// cursorFilterHash sums up the fields of the filter that select workouts.
+func cursorFilterHash(filter WorkoutFilter) string {
+	tags := slices.Clone(filter.Tags)
+	slices.Sort(tags)
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d|%s|%s|%s|%s|%s|%t",
+		filter.UserID,
+		formatCursorTime(filter.From),
+		formatCursorTime(filter.To),
+		filter.Title,
+		formatCursorInt(filter.MinDuration),
+		strings.Join(tags, "\x00"),
+		filter.MatchAllTags,
+	)
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+func formatCursorTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func formatCursorInt(i *int) string {
+	if i == nil {
+		return ""
+	}
+	return fmt.Sprint(*i)
+}
+
+func encodeCursor(c workoutCursor) string {
+	js, err := json.Marshal(c)
+	if err != nil {
+		// a struct of a time, an int, a bool and strings always marshals
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func decodeCursor(s string) (workoutCursor, error) {
+	var c workoutCursor
+
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	err = json.Unmarshal(js, &c)
+	if err != nil || c.ID <= 0 || c.CreatedAt.IsZero() {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestWorkoutCursor(t *testing.T) {
+	createdAt := time.Date(2025, 3, 14, 9, 26, 53, 589793000, time.UTC)
+
+	tests := []struct {
+		name   string
+		cursor workoutCursor
+	}{
+		{name: "forward", cursor: workoutCursor{CreatedAt: createdAt, ID: 42}},
+		{name: "backward", cursor: workoutCursor{CreatedAt: createdAt, ID: 7, Backward: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(encodeCursor(tt.cursor))
+			require.NoError(t, err)
+			assert.True(t, tt.cursor.CreatedAt.Equal(got.CreatedAt))
+			assert.Equal(t, tt.cursor.ID, got.ID)
+			assert.Equal(t, tt.cursor.Backward, got.Backward)
+		})
+	}
+
+	for _, invalid := range []string{"not-base64!", "e30", encodeCursor(workoutCursor{ID: 1})} {
+		_, err := decodeCursor(invalid)
+		assert.ErrorIs(t, err, ErrInvalidCursor, invalid)
+	}
+}
+
+func TestWorkoutCursorMatchesFilter(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	filter := WorkoutFilter{UserID: 1, Sort: "-created_at", From: &from, Tags: []string{"gym", "legs"}}
+	cursor := workoutCursor{
+		CreatedAt: from,
+		ID:        1,
+		Direction: filter.sortDirection(),
+		Filter:    cursorFilterHash(filter),
+	}
+	cursor, err := decodeCursor(encodeCursor(cursor))
+	require.NoError(t, err)
+	assert.True(t, cursor.matches(filter))
+
+	// the order of the tags does not change the listing
+	reordered := filter
+	reordered.Tags = []string{"legs", "gym"}
+	assert.True(t, cursor.matches(reordered))
+
+	ascending := filter
+	ascending.Sort = "created_at"
+	assert.False(t, cursor.matches(ascending))
+
+	otherTitle := filter
+	otherTitle.Title = "push"
+	assert.False(t, cursor.matches(otherTitle))
+
+	otherTags := filter
+	otherTags.MatchAllTags = true
+	assert.False(t, cursor.matches(otherTags))
+}
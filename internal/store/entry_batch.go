@@ -0,0 +1,257 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Entries and sets are written with one multi-row statement per batch. The
+// batch sizes keep the statements well below the limit of 65535 parameters.
+const (
+	entryBatchSize = 1000
+	setBatchSize   = 4000
+)
+
+var entryColumns = []string{
+	"exercise_id", "exercise_name", "entry_type", "sets", "reps", "duration_seconds", "weight", "weight_unit",
+	"distance", "distance_unit", "elevation_gain_meters", "avg_heart_rate", "max_heart_rate", "notes", "order_index",
+	"group_label",
+}
+
+// entryColumnTypes type the columns of a VALUES list, which postgres would
+// otherwise read as text.
+var entryColumnTypes = []string{
+	"bigint", "text", "text", "integer", "integer", "integer", "numeric", "text",
+	"numeric", "text", "integer", "integer", "integer", "text", "integer",
+	"text",
+}
+
+func entryArgs(entry *WorkoutEntry) []any {
+	return []any{
+		entry.ExerciseID,
+		entry.ExerciseName,
+		entry.EntryType,
+		entry.Sets,
+		entry.Reps,
+		entry.DurationSeconds,
+		entry.Weight,
+		entry.LoggedWeightUnit,
+		entry.Distance,
+		nullString(entry.DistanceUnit),
+		entry.ElevationGainMeters,
+		entry.AvgHeartRate,
+		entry.MaxHeartRate,
+		entry.Notes,
+		entry.OrderIndex,
+		nullString(entry.GroupLabel),
+	}
+}
+
+// valuesList renders the placeholders of a VALUES list with rows rows of
+// len(types) columns, numbered after the first offset parameters. Empty types
+// leave the column uncast.
+func valuesList(rows int, types []string, offset int) string {
+	var b strings.Builder
+	param := offset
+	for row := 0; row < rows; row++ {
+		if row > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for column, sqlType := range types {
+			if column > 0 {
+				b.WriteString(", ")
+			}
+			param++
+			fmt.Fprintf(&b, "$%d", param)
+			if sqlType != "" {
+				b.WriteString("::" + sqlType)
+			}
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+// insertEntryBatch inserts new entries of the workout together with their
+// sets. Every row of the VALUES list carries its index in the batch, which is
+// returned with the id the row was given, as order_index need not be unique.
+func insertEntryBatch(tx *sql.Tx, workoutID int, entries []*WorkoutEntry) error {
+	for _, entry := range entries {
+		err := prepareEntry(entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	columns := strings.Join(entryColumns, ", ")
+	types := append([]string{"integer"}, entryColumnTypes...)
+	for start := 0; start < len(entries); start += entryBatchSize {
+		batch := entries[start:min(start+entryBatchSize, len(entries))]
+
+		args := make([]any, 0, 1+len(batch)*len(types))
+		args = append(args, workoutID)
+		for i, entry := range batch {
+			args = append(args, i)
+			args = append(args, entryArgs(entry)...)
+		}
+
+		// the ids are drawn up front, so they can be returned along with the
+		// row index
+		query := fmt.Sprintf(`
+			WITH v AS (
+				SELECT nextval(pg_get_serial_sequence('workout_entries', 'id')) AS id, *
+				FROM (VALUES %s) AS r(row_index, %s)
+			), inserted AS (
+				INSERT INTO workout_entries (id, workout_id, %s)
+				SELECT id, $1, %s FROM v
+			)
+			SELECT id, row_index FROM v
+		`, valuesList(len(batch), types, 1), columns, columns, columns)
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var id, rowIndex int
+			err = rows.Scan(&id, &rowIndex)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			batch[rowIndex].ID = id
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		entry.derivePace()
+	}
+
+	return insertSetBatch(tx, entries)
+}
+
+// updateEntryBatch overwrites existing entries of the workout in place and
+// replaces their sets. It returns sql.ErrNoRows when an entry is not part of
+// the workout.
+func updateEntryBatch(tx *sql.Tx, workoutID int, entries []*WorkoutEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	for _, entry := range entries {
+		err := prepareEntry(entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	assignments := make([]string, len(entryColumns))
+	for i, column := range entryColumns {
+		assignments[i] = fmt.Sprintf("%s = v.%s", column, column)
+	}
+	types := append([]string{"bigint"}, entryColumnTypes...)
+
+	entryIDs := make([]int64, 0, len(entries))
+	for start := 0; start < len(entries); start += entryBatchSize {
+		batch := entries[start:min(start+entryBatchSize, len(entries))]
+
+		args := []any{workoutID}
+		for _, entry := range batch {
+			args = append(args, entry.ID)
+			args = append(args, entryArgs(entry)...)
+			entryIDs = append(entryIDs, int64(entry.ID))
+		}
+
+		query := fmt.Sprintf(`
+			UPDATE workout_entries e
+			SET %s
+			FROM (VALUES %s) AS v(id, %s)
+			WHERE e.id = v.id AND e.workout_id = $1
+		`, strings.Join(assignments, ", "), valuesList(len(batch), types, 1), strings.Join(entryColumns, ", "))
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if int(rowsAffected) != len(batch) {
+			return sql.ErrNoRows
+		}
+	}
+
+	for _, entry := range entries {
+		entry.derivePace()
+	}
+
+	_, err := tx.Exec(`DELETE FROM workout_sets WHERE workout_entry_id = ANY($1)`, entryIDs)
+	if err != nil {
+		return err
+	}
+
+	return insertSetBatch(tx, entries)
+}
+
+// insertSetBatch inserts the sets of already stored entries.
+func insertSetBatch(tx *sql.Tx, entries []*WorkoutEntry) error {
+	type setKey struct{ entryID, setNumber int }
+
+	sets := []*WorkoutSet{}
+	bySetKey := map[setKey]*WorkoutSet{}
+	args := []any{}
+	for _, entry := range entries {
+		for i := range entry.SetDetails {
+			set := &entry.SetDetails[i]
+			sets = append(sets, set)
+			bySetKey[setKey{entry.ID, set.SetNumber}] = set
+			args = append(args, entry.ID, set.SetNumber, set.Reps, set.Weight, set.DurationSeconds, set.RPE, set.RIR, set.IsWarmup)
+		}
+	}
+
+	const columns = 8
+	types := make([]string, columns)
+	for start := 0; start < len(sets); start += setBatchSize {
+		end := min(start+setBatchSize, len(sets))
+
+		query := fmt.Sprintf(`
+			INSERT INTO workout_sets (workout_entry_id, set_number, reps, weight, duration_seconds, rpe, rir, is_warmup)
+			VALUES %s
+			RETURNING id, workout_entry_id, set_number
+		`, valuesList(end-start, types, 0))
+		rows, err := tx.Query(query, args[start*columns:end*columns]...)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var id int
+			var key setKey
+			err = rows.Scan(&id, &key.entryID, &key.setNumber)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			bySetKey[key].ID = id
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValuesList(t *testing.T) {
+	assert.Equal(t, "($1, $2), ($3, $4)", valuesList(2, []string{"", ""}, 0))
+	assert.Equal(t, "($2::bigint, $3::text)", valuesList(1, []string{"bigint", "text"}, 1))
+	assert.Equal(t, "", valuesList(0, []string{"bigint"}, 0))
+}
+
+func TestInsertEntryBatchWithSharedOrderIndex(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	store := NewPostgresWorkoutStore(db)
+	user := createTestUser(t, db, "batch_user")
+
+	// clients that do not order their entries send every one with 0
+	workout, err := store.CreateWorkout(&Workout{
+		UserID: user.ID,
+		Title:  "unordered",
+		Entries: []WorkoutEntry{
+			{ExerciseName: "Squat", Sets: 1, Reps: createIntPtr(5)},
+			{ExerciseName: "Lunge", Sets: 2, Reps: createIntPtr(10)},
+			{ExerciseName: "Calf Raise", Sets: 3, Reps: createIntPtr(15)},
+		},
+	})
+	require.NoError(t, err)
+
+	saved, err := store.GetWorkoutByID(int64(workout.ID))
+	require.NoError(t, err)
+	require.Len(t, saved.Entries, 3)
+
+	byID := map[int]WorkoutEntry{}
+	for _, entry := range saved.Entries {
+		byID[entry.ID] = entry
+	}
+	for _, entry := range workout.Entries {
+		stored, ok := byID[entry.ID]
+		require.True(t, ok)
+		assert.Equal(t, entry.ExerciseName, stored.ExerciseName)
+		assert.Len(t, stored.SetDetails, entry.Sets)
+		assert.Equal(t, *entry.Reps, *stored.SetDetails[0].Reps)
+	}
+}
@@ -0,0 +1,232 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgtype"
+	"time"
+)
+
+var ErrDuplicateExercise = errors.New("an exercise with this name already exists")
+
+var ExerciseCategories = []string{"strength", "cardio", "flexibility", "plyometrics", "other"}
+
+var MuscleGroups = []string{
+	"chest", "back", "shoulders", "biceps", "triceps", "forearms", "core",
+	"glutes", "quadriceps", "hamstrings", "calves", "full_body",
+}
+
+type Exercise struct {
+	ID               int       `json:"id"`
+	UserID           *int      `json:"user_id"`
+	Name             string    `json:"name"`
+	Category         string    `json:"category"`
+	PrimaryMuscles   []string  `json:"primary_muscles"`
+	SecondaryMuscles []string  `json:"secondary_muscles"`
+	Equipment        string    `json:"equipment"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// IsCustom reports whether the exercise was defined by a user rather than
+// shipped with the built-in library.
+func (e *Exercise) IsCustom() bool {
+	return e.UserID != nil
+}
+
+type ExerciseFilter struct {
+	UserID   int
+	Name     string
+	Category string
+	Muscle   string
+}
+
+type PostgresExerciseStore struct {
+	db *sql.DB
+}
+
+func NewPostgresExerciseStore(db *sql.DB) *PostgresExerciseStore {
+	return &PostgresExerciseStore{
+		db: db,
+	}
+}
+
+type ExerciseStore interface {
+	CreateExercise(*Exercise) error
+	GetExerciseByID(id int64) (*Exercise, error)
+	ListExercises(filter ExerciseFilter) ([]*Exercise, error)
+	UpdateExercise(*Exercise) error
+	DeleteExercise(id int64) error
+}
+
+func (pg *PostgresExerciseStore) CreateExercise(exercise *Exercise) error {
+	query := `
+		INSERT INTO exercises (user_id, name, category, primary_muscles, secondary_muscles, equipment)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := pg.db.QueryRow(query,
+		exercise.UserID,
+		exercise.Name,
+		exercise.Category,
+		textArray(exercise.PrimaryMuscles),
+		textArray(exercise.SecondaryMuscles),
+		exercise.Equipment,
+	).Scan(&exercise.ID, &exercise.CreatedAt, &exercise.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicateExercise
+	}
+
+	return err
+}
+
+func (pg *PostgresExerciseStore) GetExerciseByID(id int64) (*Exercise, error) {
+	query := `
+		SELECT id, user_id, name, category, primary_muscles, secondary_muscles, equipment, created_at, updated_at
+		FROM exercises
+		WHERE id = $1
+	`
+
+	exercise, err := scanExercise(pg.db.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return exercise, nil
+}
+
+// ListExercises returns the built-in library together with the custom
+// exercises of filter.UserID.
+func (pg *PostgresExerciseStore) ListExercises(filter ExerciseFilter) ([]*Exercise, error) {
+	query := `
+		SELECT id, user_id, name, category, primary_muscles, secondary_muscles, equipment, created_at, updated_at
+		FROM exercises
+		WHERE (user_id IS NULL OR user_id = $1)
+		AND ($2 = '' OR name ILIKE '%' || $2 || '%')
+		AND ($3 = '' OR category = $3)
+		AND ($4 = '' OR $4 = ANY(primary_muscles) OR $4 = ANY(secondary_muscles))
+		ORDER BY lower(name), id
+	`
+
+	rows, err := pg.db.Query(query, filter.UserID, escapeLike(filter.Name), filter.Category, filter.Muscle)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exercises := []*Exercise{}
+	for rows.Next() {
+		exercise, err := scanExercise(rows)
+		if err != nil {
+			return nil, err
+		}
+		exercises = append(exercises, exercise)
+	}
+
+	return exercises, rows.Err()
+}
+
+func (pg *PostgresExerciseStore) UpdateExercise(exercise *Exercise) error {
+	query := `
+		UPDATE exercises
+		SET name = $1, category = $2, primary_muscles = $3, secondary_muscles = $4, equipment = $5,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6 AND user_id IS NOT NULL
+		RETURNING updated_at
+	`
+
+	err := pg.db.QueryRow(query,
+		exercise.Name,
+		exercise.Category,
+		textArray(exercise.PrimaryMuscles),
+		textArray(exercise.SecondaryMuscles),
+		exercise.Equipment,
+		exercise.ID,
+	).Scan(&exercise.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicateExercise
+	}
+
+	return err
+}
+
+func (pg *PostgresExerciseStore) DeleteExercise(id int64) error {
+	query := `
+		DELETE FROM exercises WHERE id = $1 AND user_id IS NOT NULL
+	`
+
+	result, err := pg.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+	resultRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if resultRows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanExercise(row rowScanner) (*Exercise, error) {
+	exercise := &Exercise{}
+	var primary, secondary pgtype.TextArray
+
+	err := row.Scan(
+		&exercise.ID,
+		&exercise.UserID,
+		&exercise.Name,
+		&exercise.Category,
+		&primary,
+		&secondary,
+		&exercise.Equipment,
+		&exercise.CreatedAt,
+		&exercise.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = primary.AssignTo(&exercise.PrimaryMuscles)
+	if err != nil {
+		return nil, err
+	}
+	err = secondary.AssignTo(&exercise.SecondaryMuscles)
+	if err != nil {
+		return nil, err
+	}
+
+	return exercise, nil
+}
+
+func textArray(values []string) *pgtype.TextArray {
+	array := &pgtype.TextArray{}
+	if values == nil {
+		values = []string{}
+	}
+	// Set only fails for unsupported source types
+	_ = array.Set(values)
+	return array
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestExerciseStore(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	store := NewPostgresExerciseStore(db)
+	user := createTestUser(t, db, "exercise_owner")
+	other := createTestUser(t, db, "exercise_other")
+
+	custom := &Exercise{
+		UserID:         &user.ID,
+		Name:           "Landmine Press",
+		Category:       "strength",
+		PrimaryMuscles: []string{"shoulders"},
+		Equipment:      "barbell",
+	}
+	require.NoError(t, store.CreateExercise(custom))
+	assert.NotZero(t, custom.ID)
+
+	duplicate := &Exercise{UserID: &user.ID, Name: "landmine press", Category: "strength"}
+	assert.ErrorIs(t, store.CreateExercise(duplicate), ErrDuplicateExercise)
+
+	// names are unique per user, not across users
+	require.NoError(t, store.CreateExercise(&Exercise{UserID: &other.ID, Name: "Landmine Press", Category: "strength"}))
+
+	fetched, err := store.GetExerciseByID(int64(custom.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "Landmine Press", fetched.Name)
+	assert.Equal(t, []string{"shoulders"}, fetched.PrimaryMuscles)
+	assert.True(t, fetched.IsCustom())
+
+	missing, err := store.GetExerciseByID(-1)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	exercises, err := store.ListExercises(ExerciseFilter{UserID: user.ID, Name: "press", Muscle: "shoulders"})
+	require.NoError(t, err)
+	names := map[string]int{}
+	for _, exercise := range exercises {
+		names[exercise.Name]++
+	}
+	assert.Equal(t, 1, names["Landmine Press"], "the other user's exercise is not listed")
+	assert.Equal(t, 1, names["Bench Press"], "the library is listed")
+
+	cardio, err := store.ListExercises(ExerciseFilter{UserID: user.ID, Category: "cardio"})
+	require.NoError(t, err)
+	for _, exercise := range cardio {
+		assert.Equal(t, "cardio", exercise.Category)
+	}
+
+	custom.Name = "Half Kneeling Landmine Press"
+	require.NoError(t, store.UpdateExercise(custom))
+	fetched, err = store.GetExerciseByID(int64(custom.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "Half Kneeling Landmine Press", fetched.Name)
+
+	library := exercises[0]
+	for _, exercise := range exercises {
+		if !exercise.IsCustom() {
+			library = exercise
+		}
+	}
+	require.False(t, library.IsCustom())
+	assert.ErrorIs(t, store.UpdateExercise(library), sql.ErrNoRows)
+	assert.ErrorIs(t, store.DeleteExercise(int64(library.ID)), sql.ErrNoRows)
+
+	require.NoError(t, store.DeleteExercise(int64(custom.ID)))
+	assert.ErrorIs(t, store.DeleteExercise(int64(custom.ID)), sql.ErrNoRows)
+	fetched, err = store.GetExerciseByID(int64(custom.ID))
+	require.NoError(t, err)
+	assert.Nil(t, fetched)
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// WorkoutSortSafelist holds the sort values accepted by ListWorkouts. A
+// leading "-" sorts in descending order.
+var WorkoutSortSafelist = []string{
+	"date", "performed", "duration", "calories",
+	"-date", "-performed", "-duration", "-calories",
+}
+
+var workoutSortColumns = map[string]string{
+	"date":      "created_at",
+	"performed": "performed_at",
+	"duration":  "duration_minutes",
+	"calories":  "calories_burned",
+}
+
+type WorkoutFilter struct {
+	UserID int
+	Page   int
+	Limit  int
+	Sort   string
+	// From and To bound performed_at, To being exclusive.
+	From        *time.Time
+	To          *time.Time
+	Title       string
+	MinDuration *int
+	// Tags limits the listing to workouts carrying any of the tags, or all
+	// of them with MatchAllTags.
+	Tags         []string
+	MatchAllTags bool
+	// Cursor switches the listing to keyset pagination, in which case Page
+	// is ignored. It requires sorting by date.
+	Cursor string
+}
+
+// CursorSortable reports whether the filter's sort order supports cursor
+// pagination, which is keyed on (created_at, id).
+func (f WorkoutFilter) CursorSortable() bool {
+	return f.sortColumn() == "created_at"
+}
+
+func (f WorkoutFilter) sortColumn() string {
+	column, ok := workoutSortColumns[strings.TrimPrefix(f.Sort, "-")]
+	if !ok {
+		return "created_at"
+	}
+	return column
+}
+
+func (f WorkoutFilter) sortDirection() string {
+	if strings.HasPrefix(f.Sort, "-") {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+func (f WorkoutFilter) limit() int {
+	return f.Limit
+}
+
+func (f WorkoutFilter) offset() int {
+	return (f.Page - 1) * f.Limit
+}
+
+type Metadata struct {
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"-"`
+	PrevCursor   string `json:"-"`
+}
+
+func calculateMetadata(totalRecords, page, limit int) Metadata {
+	if totalRecords == 0 {
+		return Metadata{}
+	}
+
+	return Metadata{
+		CurrentPage:  page,
+		PageSize:     limit,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(limit))),
+		TotalRecords: totalRecords,
+	}
+}
+
+// escapeLike escapes the LIKE wildcards in s so it can be matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
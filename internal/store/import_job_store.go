@@ -0,0 +1,164 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// ImportRowError explains why a row of an imported file was skipped. Rows are
+// numbered like the lines of the file, the header being row 1.
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ImportJob tracks an import running in the background. Errors holds the
+// first of the row errors only, RowsFailed counts all of them. Error is set
+// when the job failed as a whole.
+type ImportJob struct {
+	ID                int              `json:"id"`
+	UserID            int              `json:"user_id"`
+	Status            string           `json:"status"`
+	Filename          string           `json:"filename"`
+	RowsProcessed     int              `json:"rows_processed"`
+	RowsFailed        int              `json:"rows_failed"`
+	WorkoutsImported  int              `json:"workouts_imported"`
+	DuplicatesSkipped int              `json:"duplicates_skipped"`
+	Errors            []ImportRowError `json:"errors"`
+	Error             string           `json:"error,omitempty"`
+	CreatedAt         time.Time        `json:"created_at"`
+	StartedAt         *time.Time       `json:"started_at"`
+	FinishedAt        *time.Time       `json:"finished_at"`
+}
+
+type PostgresImportJobStore struct {
+	db *sql.DB
+}
+
+func NewPostgresImportJobStore(db *sql.DB) *PostgresImportJobStore {
+	return &PostgresImportJobStore{
+		db: db,
+	}
+}
+
+type ImportJobStore interface {
+	CreateImportJob(job *ImportJob) error
+	GetImportJob(id int64) (*ImportJob, error)
+	UpdateImportJob(job *ImportJob) error
+	FailUnfinishedImportJobs(reason string) (int64, error)
+}
+
+func (pg *PostgresImportJobStore) CreateImportJob(job *ImportJob) error {
+	if job.Status == "" {
+		job.Status = ImportPending
+	}
+	if job.Errors == nil {
+		job.Errors = []ImportRowError{}
+	}
+
+	query := `
+		INSERT INTO import_jobs (user_id, status, filename)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+	return pg.db.QueryRow(query, job.UserID, job.Status, job.Filename).Scan(&job.ID, &job.CreatedAt)
+}
+
+func (pg *PostgresImportJobStore) GetImportJob(id int64) (*ImportJob, error) {
+	job := &ImportJob{}
+	var rowErrors []byte
+	query := `
+		SELECT id, user_id, status, filename, rows_processed, rows_failed, workouts_imported,
+			duplicates_skipped, errors, error, created_at, started_at, finished_at
+		FROM import_jobs
+		WHERE id = $1
+	`
+	err := pg.db.QueryRow(query, id).Scan(
+		&job.ID,
+		&job.UserID,
+		&job.Status,
+		&job.Filename,
+		&job.RowsProcessed,
+		&job.RowsFailed,
+		&job.WorkoutsImported,
+		&job.DuplicatesSkipped,
+		&rowErrors,
+		&job.Error,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.FinishedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(rowErrors, &job.Errors)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// UpdateImportJob saves the status and progress of the job.
+func (pg *PostgresImportJobStore) UpdateImportJob(job *ImportJob) error {
+	if job.Errors == nil {
+		job.Errors = []ImportRowError{}
+	}
+	rowErrors, err := json.Marshal(job.Errors)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE import_jobs
+		SET status = $1, rows_processed = $2, rows_failed = $3, workouts_imported = $4,
+			duplicates_skipped = $5, errors = $6::jsonb, error = $7, started_at = $8, finished_at = $9
+		WHERE id = $10
+	`
+	result, err := pg.db.Exec(query, job.Status, job.RowsProcessed, job.RowsFailed, job.WorkoutsImported,
+		job.DuplicatesSkipped, string(rowErrors), job.Error, job.StartedAt, job.FinishedAt, job.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// FailUnfinishedImportJobs marks the jobs that are still pending or running
+// as failed. Jobs only run inside the process that started them, so this is
+// meant for startup, when such jobs can no longer finish.
+func (pg *PostgresImportJobStore) FailUnfinishedImportJobs(reason string) (int64, error) {
+	query := `
+		UPDATE import_jobs
+		SET status = $1, error = $2, finished_at = CURRENT_TIMESTAMP
+		WHERE status IN ($3, $4)
+	`
+	result, err := pg.db.Exec(query, ImportFailed, reason, ImportPending, ImportRunning)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
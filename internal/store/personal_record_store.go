@@ -0,0 +1,261 @@
+package store
+
+import (
+	"cmp"
+	"database/sql"
+	"github.com/helmigandi/go-workout-api/internal/fitness"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	RecordMaxWeight       = "max_weight"
+	RecordMaxRepsAtWeight = "max_reps_at_weight"
+	RecordEstimated1RM    = "estimated_1rm"
+	RecordMaxDuration     = "max_duration"
+)
+
+type PersonalRecord struct {
+	ID             int       `json:"id"`
+	WorkoutID      int       `json:"workout_id"`
+	WorkoutEntryID *int      `json:"workout_entry_id"`
+	ExerciseID     *int      `json:"exercise_id"`
+	ExerciseName   string    `json:"exercise_name"`
+	RecordType     string    `json:"record_type"`
+	Value          float64   `json:"value"`
+	Weight         *float64  `json:"weight"`
+	WeightUnit     string    `json:"weight_unit"`
+	AchievedAt     time.Time `json:"achieved_at"`
+}
+
+type PostgresPersonalRecordStore struct {
+	db *sql.DB
+}
+
+func NewPostgresPersonalRecordStore(db *sql.DB) *PostgresPersonalRecordStore {
+	return &PostgresPersonalRecordStore{
+		db: db,
+	}
+}
+
+type PersonalRecordStore interface {
+	DetectPersonalRecords(workout *Workout) ([]PersonalRecord, error)
+	ListPersonalRecords(userID int, exercise string) ([]PersonalRecord, error)
+}
+
+// exerciseBest holds the best marks of one exercise within a workout along
+// with the entry each was set in. When an exercise is logged in several
+// entries, a tie goes to the entry that comes first.
+type exerciseBest struct {
+	entry             *WorkoutEntry
+	maxWeight         float64
+	maxWeightEntry    *WorkoutEntry
+	oneRepMax         float64
+	oneRepMaxEntry    *WorkoutEntry
+	maxDuration       float64
+	maxDurationEntry  *WorkoutEntry
+	repsAtWeight      map[float64]int
+	repsAtWeightEntry map[float64]*WorkoutEntry
+}
+
+// bestsByExercise collects the best working-set marks of every exercise in the
+// workout, keyed by lower-cased exercise name.
+func bestsByExercise(workout *Workout) map[string]*exerciseBest {
+	bests := map[string]*exerciseBest{}
+	for i := range workout.Entries {
+		entry := &workout.Entries[i]
+		key := strings.ToLower(strings.TrimSpace(entry.ExerciseName))
+		best, ok := bests[key]
+		if !ok {
+			best = &exerciseBest{
+				entry:             entry,
+				repsAtWeight:      map[float64]int{},
+				repsAtWeightEntry: map[float64]*WorkoutEntry{},
+			}
+			bests[key] = best
+		}
+
+		for _, set := range entry.SetDetails {
+			if set.IsWarmup {
+				continue
+			}
+			weight, reps := valueOrZero(set.Weight), valueOrZero(set.Reps)
+			if weight > 0 && reps > 0 {
+				if weight > best.maxWeight {
+					best.maxWeight, best.maxWeightEntry = weight, entry
+				}
+				if oneRepMax := fitness.EstimateOneRepMax(weight, reps, fitness.FormulaEpley); oneRepMax > best.oneRepMax {
+					best.oneRepMax, best.oneRepMaxEntry = oneRepMax, entry
+				}
+				if reps > best.repsAtWeight[weight] {
+					best.repsAtWeight[weight], best.repsAtWeightEntry[weight] = reps, entry
+				}
+			}
+			if duration := float64(valueOrZero(set.DurationSeconds)); duration > best.maxDuration {
+				best.maxDuration, best.maxDurationEntry = duration, entry
+			}
+		}
+	}
+	return bests
+}
+
+// DetectPersonalRecords compares the workout against the workouts its owner
+// performed before it and stores the marks it beats. Records previously set
+// by the workout are replaced, so it can run again after an update.
+func (pg *PostgresPersonalRecordStore) DetectPersonalRecords(workout *Workout) ([]PersonalRecord, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM personal_records WHERE workout_id = $1`, workout.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []PersonalRecord{}
+	for key, best := range bestsByExercise(workout) {
+		history := `
+			SELECT COALESCE(max(s.weight), 0),
+				COALESCE(max(CASE WHEN s.reps = 1 THEN s.weight ELSE s.weight * (1 + s.reps / 30.0) END), 0),
+				COALESCE(max(s.duration_seconds), 0)
+			FROM workout_sets s
+			JOIN workout_entries e ON e.id = s.workout_entry_id
+			JOIN workouts w ON w.id = e.workout_id
+			WHERE w.user_id = $1 AND (w.performed_at, w.id) < ($5, $2) AND w.deleted_at IS NULL AND NOT s.is_warmup
+			AND (e.exercise_id = $3 OR lower(e.exercise_name) = $4)
+		`
+		var maxWeight, oneRepMax, maxDuration float64
+		err = tx.QueryRow(history, workout.UserID, workout.ID, best.entry.ExerciseID, key, workout.PerformedAt).
+			Scan(&maxWeight, &oneRepMax, &maxDuration)
+		if err != nil {
+			return nil, err
+		}
+
+		newRecord := func(entry *WorkoutEntry, recordType string, value float64, weight *float64) PersonalRecord {
+			return PersonalRecord{
+				WorkoutID:      workout.ID,
+				WorkoutEntryID: &entry.ID,
+				ExerciseID:     best.entry.ExerciseID,
+				ExerciseName:   best.entry.ExerciseName,
+				RecordType:     recordType,
+				Value:          value,
+				Weight:         weight,
+				WeightUnit:     fitness.UnitKilogram,
+				AchievedAt:     workout.PerformedAt,
+			}
+		}
+
+		if best.maxWeight > maxWeight {
+			records = append(records, newRecord(best.maxWeightEntry, RecordMaxWeight, best.maxWeight, nil))
+		}
+		// compare rounded to the stored precision so equal lifts never count
+		if roundTo(best.oneRepMax, 2) > roundTo(oneRepMax, 2) {
+			records = append(records, newRecord(best.oneRepMaxEntry, RecordEstimated1RM, roundTo(best.oneRepMax, 2), nil))
+		}
+		if best.maxDuration > maxDuration {
+			records = append(records, newRecord(best.maxDurationEntry, RecordMaxDuration, best.maxDuration, nil))
+		}
+
+		for weight, reps := range best.repsAtWeight {
+			var maxReps int
+			query := `
+				SELECT COALESCE(max(s.reps), 0)
+				FROM workout_sets s
+				JOIN workout_entries e ON e.id = s.workout_entry_id
+				JOIN workouts w ON w.id = e.workout_id
+				WHERE w.user_id = $1 AND (w.performed_at, w.id) < ($6, $2) AND w.deleted_at IS NULL
+				AND NOT s.is_warmup AND s.weight = $5
+				AND (e.exercise_id = $3 OR lower(e.exercise_name) = $4)
+			`
+			err = tx.QueryRow(query, workout.UserID, workout.ID, best.entry.ExerciseID, key, weight, workout.PerformedAt).
+				Scan(&maxReps)
+			if err != nil {
+				return nil, err
+			}
+			if reps > maxReps {
+				records = append(records, newRecord(best.repsAtWeightEntry[weight], RecordMaxRepsAtWeight, float64(reps), &weight))
+			}
+		}
+	}
+
+	slices.SortFunc(records, func(a, b PersonalRecord) int {
+		return cmp.Or(
+			cmp.Compare(a.ExerciseName, b.ExerciseName),
+			cmp.Compare(a.RecordType, b.RecordType),
+			cmp.Compare(valueOrZero(a.Weight), valueOrZero(b.Weight)),
+		)
+	})
+
+	for i := range records {
+		record := &records[i]
+		query := `
+			INSERT INTO personal_records (user_id, workout_id, workout_entry_id, exercise_id, exercise_name,
+				record_type, value, weight, achieved_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id
+		`
+		err = tx.QueryRow(query,
+			workout.UserID,
+			record.WorkoutID,
+			record.WorkoutEntryID,
+			record.ExerciseID,
+			record.ExerciseName,
+			record.RecordType,
+			record.Value,
+			record.Weight,
+			record.AchievedAt).
+			Scan(&record.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// ListPersonalRecords returns the record history of userID, newest first. An
+// empty exercise lists every exercise.
+func (pg *PostgresPersonalRecordStore) ListPersonalRecords(userID int, exercise string) ([]PersonalRecord, error) {
+	query := `
+		SELECT r.id, r.workout_id, r.workout_entry_id, r.exercise_id, r.exercise_name, r.record_type, r.value, r.weight, r.achieved_at
+		FROM personal_records r
+		JOIN workouts w ON w.id = r.workout_id AND w.deleted_at IS NULL
+		WHERE r.user_id = $1 AND ($2 = '' OR lower(r.exercise_name) = lower($2))
+		ORDER BY lower(r.exercise_name), r.record_type, r.achieved_at DESC, r.id DESC
+	`
+	rows, err := pg.db.Query(query, userID, exercise)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []PersonalRecord{}
+	for rows.Next() {
+		record := PersonalRecord{WeightUnit: fitness.UnitKilogram}
+		err = rows.Scan(
+			&record.ID,
+			&record.WorkoutID,
+			&record.WorkoutEntryID,
+			&record.ExerciseID,
+			&record.ExerciseName,
+			&record.RecordType,
+			&record.Value,
+			&record.Weight,
+			&record.AchievedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
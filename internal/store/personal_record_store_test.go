@@ -0,0 +1,52 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestBestsByExercise(t *testing.T) {
+	workout := &Workout{
+		Entries: []WorkoutEntry{
+			{
+				ExerciseName: "Bench Press",
+				SetDetails: []WorkoutSet{
+					{Reps: createIntPtr(10), Weight: createFloatPtr(100), IsWarmup: true},
+					{Reps: createIntPtr(8), Weight: createFloatPtr(80)},
+					{Reps: createIntPtr(5), Weight: createFloatPtr(90)},
+				},
+			},
+			{
+				ExerciseName: "bench press ",
+				SetDetails: []WorkoutSet{
+					{Reps: createIntPtr(10), Weight: createFloatPtr(80)},
+				},
+			},
+			{
+				ExerciseName: "Plank",
+				SetDetails: []WorkoutSet{
+					{DurationSeconds: createIntPtr(60)},
+					{DurationSeconds: createIntPtr(75)},
+				},
+			},
+		},
+	}
+
+	bests := bestsByExercise(workout)
+	require.Len(t, bests, 2)
+
+	bench := bests["bench press"]
+	assert.Equal(t, 90.0, bench.maxWeight)
+	assert.InDelta(t, 106.67, bench.oneRepMax, 0.01)
+	assert.Equal(t, map[float64]int{80: 10, 90: 5}, bench.repsAtWeight)
+	// each mark points at the entry it was set in
+	assert.Same(t, &workout.Entries[0], bench.maxWeightEntry)
+	assert.Same(t, &workout.Entries[1], bench.oneRepMaxEntry)
+	assert.Same(t, &workout.Entries[1], bench.repsAtWeightEntry[80])
+
+	plank := bests["plank"]
+	assert.Equal(t, 75.0, plank.maxDuration)
+	assert.Same(t, &workout.Entries[2], plank.maxDurationEntry)
+	assert.Zero(t, plank.maxWeight)
+}
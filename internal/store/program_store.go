@@ -0,0 +1,476 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrUnknownTemplate   = errors.New("unknown template")
+	ErrUnknownProgramDay = errors.New("unknown program day")
+)
+
+type Program struct {
+	ID            int          `json:"id"`
+	UserID        int          `json:"user_id"`
+	Name          string       `json:"name"`
+	Description   string       `json:"description"`
+	DurationWeeks int          `json:"duration_weeks"`
+	Days          []ProgramDay `json:"days"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// ProgramDay schedules a template on a day (1-7) of a program week.
+type ProgramDay struct {
+	ID         int    `json:"id"`
+	WeekNumber int    `json:"week_number"`
+	DayNumber  int    `json:"day_number"`
+	TemplateID int    `json:"template_id"`
+	Name       string `json:"name"`
+}
+
+// ScheduledDate returns the date the day falls on for an enrollment starting
+// on start.
+func (d ProgramDay) ScheduledDate(start time.Time) time.Time {
+	return start.AddDate(0, 0, (d.WeekNumber-1)*7+d.DayNumber-1)
+}
+
+type Enrollment struct {
+	ID        int       `json:"id"`
+	ProgramID int       `json:"program_id"`
+	UserID    int       `json:"user_id"`
+	StartDate time.Time `json:"start_date"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ProgramSession is a program day as seen from an enrollment.
+type ProgramSession struct {
+	ProgramDay
+	ScheduledDate time.Time  `json:"scheduled_date"`
+	WorkoutID     *int       `json:"workout_id"`
+	CompletedAt   *time.Time `json:"completed_at"`
+}
+
+func (s *ProgramSession) Completed() bool {
+	return s.WorkoutID != nil
+}
+
+type ProgramProgress struct {
+	EnrollmentID      int             `json:"enrollment_id"`
+	TotalSessions     int             `json:"total_sessions"`
+	CompletedSessions int             `json:"completed_sessions"`
+	MissedSessions    int             `json:"missed_sessions"`
+	PercentComplete   float64         `json:"percent_complete"`
+	CurrentWeek       int             `json:"current_week"`
+	NextSession       *ProgramSession `json:"next_session"`
+}
+
+// SessionOn returns the session scheduled on the given date, or nil for a
+// rest day. sessions must be ordered by schedule as ListSessions returns them.
+func SessionOn(sessions []*ProgramSession, date time.Time) *ProgramSession {
+	date = truncateDate(date)
+	for _, session := range sessions {
+		if session.ScheduledDate.Equal(date) {
+			return session
+		}
+	}
+	return nil
+}
+
+// CalculateProgress summarises how far the enrollment has come through its
+// sessions as of today.
+func CalculateProgress(enrollment *Enrollment, durationWeeks int, sessions []*ProgramSession, today time.Time) ProgramProgress {
+	today = truncateDate(today)
+	progress := ProgramProgress{
+		EnrollmentID:  enrollment.ID,
+		TotalSessions: len(sessions),
+	}
+
+	for _, session := range sessions {
+		switch {
+		case session.Completed():
+			progress.CompletedSessions++
+		case session.ScheduledDate.Before(today):
+			progress.MissedSessions++
+		case progress.NextSession == nil:
+			progress.NextSession = session
+		}
+	}
+
+	if progress.TotalSessions > 0 {
+		progress.PercentComplete = float64(progress.CompletedSessions) / float64(progress.TotalSessions) * 100
+	}
+
+	// CurrentWeek stays 0 until the program has started
+	days := int(today.Sub(truncateDate(enrollment.StartDate)).Hours() / 24)
+	if days >= 0 {
+		progress.CurrentWeek = min(days/7+1, durationWeeks)
+	}
+
+	return progress
+}
+
+func truncateDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+type PostgresProgramStore struct {
+	db *sql.DB
+}
+
+func NewPostgresProgramStore(db *sql.DB) *PostgresProgramStore {
+	return &PostgresProgramStore{
+		db: db,
+	}
+}
+
+type ProgramStore interface {
+	CreateProgram(*Program) error
+	GetProgramByID(id int64) (*Program, error)
+	ListPrograms(userID int) ([]*Program, error)
+	DeleteProgram(id int64) error
+	CreateEnrollment(*Enrollment) error
+	GetEnrollmentByID(id int64) (*Enrollment, error)
+	ListEnrollments(userID int) ([]*Enrollment, error)
+	ListSessions(enrollmentID int64) ([]*ProgramSession, error)
+	CompleteSession(enrollmentID, programDayID, workoutID int64) (*ProgramSession, error)
+}
+
+func (pg *PostgresProgramStore) CreateProgram(program *Program) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	templateIDs := []int64{}
+	for _, day := range program.Days {
+		templateIDs = append(templateIDs, int64(day.TemplateID))
+	}
+	var owned int
+	err = tx.QueryRow(`SELECT count(*) FROM workout_templates WHERE id = ANY($1) AND user_id = $2`, templateIDs, program.UserID).
+		Scan(&owned)
+	if err != nil {
+		return err
+	}
+	if owned != countDistinct(templateIDs) {
+		return ErrUnknownTemplate
+	}
+
+	query := `
+		INSERT INTO training_programs (user_id, name, description, duration_weeks)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`
+	err = tx.QueryRow(query, program.UserID, program.Name, program.Description, program.DurationWeeks).
+		Scan(&program.ID, &program.CreatedAt, &program.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	for i := range program.Days {
+		day := &program.Days[i]
+		query := `
+			INSERT INTO program_days (program_id, week_number, day_number, template_id, name)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`
+		err = tx.QueryRow(query, program.ID, day.WeekNumber, day.DayNumber, day.TemplateID, day.Name).Scan(&day.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (pg *PostgresProgramStore) GetProgramByID(id int64) (*Program, error) {
+	program := &Program{}
+	query := `
+		SELECT id, user_id, name, description, duration_weeks, created_at, updated_at
+		FROM training_programs
+		WHERE id = $1
+	`
+	err := pg.db.QueryRow(query, id).Scan(
+		&program.ID,
+		&program.UserID,
+		&program.Name,
+		&program.Description,
+		&program.DurationWeeks,
+		&program.CreatedAt,
+		&program.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := pg.loadProgramDays([]int64{id})
+	if err != nil {
+		return nil, err
+	}
+	program.Days = days[program.ID]
+
+	return program, nil
+}
+
+func (pg *PostgresProgramStore) ListPrograms(userID int) ([]*Program, error) {
+	query := `
+		SELECT id, user_id, name, description, duration_weeks, created_at, updated_at
+		FROM training_programs
+		WHERE user_id = $1
+		ORDER BY lower(name), id
+	`
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	programs := []*Program{}
+	ids := []int64{}
+	for rows.Next() {
+		program := &Program{}
+		err = rows.Scan(
+			&program.ID,
+			&program.UserID,
+			&program.Name,
+			&program.Description,
+			&program.DurationWeeks,
+			&program.CreatedAt,
+			&program.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		programs = append(programs, program)
+		ids = append(ids, int64(program.ID))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	days, err := pg.loadProgramDays(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, program := range programs {
+		program.Days = days[program.ID]
+	}
+
+	return programs, nil
+}
+
+func (pg *PostgresProgramStore) DeleteProgram(id int64) error {
+	result, err := pg.db.Exec(`DELETE FROM training_programs WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	resultRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if resultRows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (pg *PostgresProgramStore) CreateEnrollment(enrollment *Enrollment) error {
+	query := `
+		INSERT INTO program_enrollments (program_id, user_id, start_date)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+	return pg.db.QueryRow(query, enrollment.ProgramID, enrollment.UserID, enrollment.StartDate).
+		Scan(&enrollment.ID, &enrollment.CreatedAt)
+}
+
+func (pg *PostgresProgramStore) GetEnrollmentByID(id int64) (*Enrollment, error) {
+	enrollment := &Enrollment{}
+	query := `
+		SELECT id, program_id, user_id, start_date, created_at
+		FROM program_enrollments
+		WHERE id = $1
+	`
+	err := pg.db.QueryRow(query, id).Scan(
+		&enrollment.ID,
+		&enrollment.ProgramID,
+		&enrollment.UserID,
+		&enrollment.StartDate,
+		&enrollment.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return enrollment, nil
+}
+
+func (pg *PostgresProgramStore) ListEnrollments(userID int) ([]*Enrollment, error) {
+	query := `
+		SELECT id, program_id, user_id, start_date, created_at
+		FROM program_enrollments
+		WHERE user_id = $1
+		ORDER BY start_date DESC, id DESC
+	`
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	enrollments := []*Enrollment{}
+	for rows.Next() {
+		enrollment := &Enrollment{}
+		err = rows.Scan(
+			&enrollment.ID,
+			&enrollment.ProgramID,
+			&enrollment.UserID,
+			&enrollment.StartDate,
+			&enrollment.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		enrollments = append(enrollments, enrollment)
+	}
+
+	return enrollments, rows.Err()
+}
+
+// ListSessions returns every day of the enrolled program in schedule order,
+// along with the workout that completed it, if any.
+func (pg *PostgresProgramStore) ListSessions(enrollmentID int64) ([]*ProgramSession, error) {
+	query := `
+		SELECT d.id, d.week_number, d.day_number, d.template_id, d.name,
+			e.start_date + ((d.week_number - 1) * 7 + d.day_number - 1),
+			s.workout_id, s.completed_at
+		FROM program_enrollments e
+		JOIN program_days d ON d.program_id = e.program_id
+		LEFT JOIN program_sessions s ON s.enrollment_id = e.id AND s.program_day_id = d.id
+		WHERE e.id = $1
+		ORDER BY d.week_number, d.day_number
+	`
+	rows, err := pg.db.Query(query, enrollmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*ProgramSession{}
+	for rows.Next() {
+		session := &ProgramSession{}
+		err = rows.Scan(
+			&session.ID,
+			&session.WeekNumber,
+			&session.DayNumber,
+			&session.TemplateID,
+			&session.Name,
+			&session.ScheduledDate,
+			&session.WorkoutID,
+			&session.CompletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// CompleteSession links a logged workout to a day of the enrolled program,
+// replacing any workout that completed it before.
+func (pg *PostgresProgramStore) CompleteSession(enrollmentID, programDayID, workoutID int64) (*ProgramSession, error) {
+	session := &ProgramSession{}
+	query := `
+		INSERT INTO program_sessions (enrollment_id, program_day_id, workout_id)
+		SELECT e.id, d.id, $3
+		FROM program_enrollments e
+		JOIN program_days d ON d.program_id = e.program_id
+		WHERE e.id = $1 AND d.id = $2
+		ON CONFLICT (enrollment_id, program_day_id)
+		DO UPDATE SET workout_id = EXCLUDED.workout_id, completed_at = CURRENT_TIMESTAMP
+		RETURNING workout_id, completed_at
+	`
+	err := pg.db.QueryRow(query, enrollmentID, programDayID, workoutID).Scan(&session.WorkoutID, &session.CompletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknownProgramDay
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT d.id, d.week_number, d.day_number, d.template_id, d.name,
+			e.start_date + ((d.week_number - 1) * 7 + d.day_number - 1)
+		FROM program_enrollments e
+		JOIN program_days d ON d.program_id = e.program_id
+		WHERE e.id = $1 AND d.id = $2
+	`
+	err = pg.db.QueryRow(query, enrollmentID, programDayID).Scan(
+		&session.ID,
+		&session.WeekNumber,
+		&session.DayNumber,
+		&session.TemplateID,
+		&session.Name,
+		&session.ScheduledDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// loadProgramDays fetches the days of every given program, keyed by program id.
+func (pg *PostgresProgramStore) loadProgramDays(programIDs []int64) (map[int][]ProgramDay, error) {
+	days := make(map[int][]ProgramDay, len(programIDs))
+	if len(programIDs) == 0 {
+		return days, nil
+	}
+
+	query := `
+		SELECT program_id, id, week_number, day_number, template_id, name
+		FROM program_days
+		WHERE program_id = ANY($1)
+		ORDER BY program_id, week_number, day_number
+	`
+	rows, err := pg.db.Query(query, programIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var programID int
+		var day ProgramDay
+		err = rows.Scan(&programID, &day.ID, &day.WeekNumber, &day.DayNumber, &day.TemplateID, &day.Name)
+		if err != nil {
+			return nil, err
+		}
+		days[programID] = append(days[programID], day)
+	}
+
+	return days, rows.Err()
+}
+
+func countDistinct(ids []int64) int {
+	seen := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		seen[id] = struct{}{}
+	}
+	return len(seen)
+}
@@ -0,0 +1,40 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCalculateProgress(t *testing.T) {
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	days := []ProgramDay{
+		{ID: 1, WeekNumber: 1, DayNumber: 1},
+		{ID: 2, WeekNumber: 1, DayNumber: 3},
+		{ID: 3, WeekNumber: 2, DayNumber: 1},
+		{ID: 4, WeekNumber: 2, DayNumber: 3},
+	}
+
+	sessions := []*ProgramSession{}
+	for _, day := range days {
+		sessions = append(sessions, &ProgramSession{ProgramDay: day, ScheduledDate: day.ScheduledDate(start)})
+	}
+	sessions[0].WorkoutID = createIntPtr(10)
+
+	enrollment := &Enrollment{ID: 1, StartDate: start}
+	today := time.Date(2025, 1, 13, 18, 30, 0, 0, time.UTC)
+
+	progress := CalculateProgress(enrollment, 2, sessions, today)
+	assert.Equal(t, 4, progress.TotalSessions)
+	assert.Equal(t, 1, progress.CompletedSessions)
+	assert.Equal(t, 1, progress.MissedSessions)
+	assert.Equal(t, 25.0, progress.PercentComplete)
+	assert.Equal(t, 2, progress.CurrentWeek)
+	assert.Equal(t, 3, progress.NextSession.ID)
+
+	assert.Equal(t, sessions[2], SessionOn(sessions, today))
+	assert.Nil(t, SessionOn(sessions, today.AddDate(0, 0, 1)))
+
+	before := CalculateProgress(enrollment, 2, sessions, start.AddDate(0, 0, -3))
+	assert.Equal(t, 0, before.CurrentWeek)
+}
@@ -0,0 +1,350 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/helmigandi/go-workout-api/internal/fitness"
+	"time"
+)
+
+var ErrTemplateInUse = errors.New("template is used by a training program")
+
+type WorkoutTemplate struct {
+	ID          int                `json:"id"`
+	UserID      int                `json:"user_id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Exercises   []TemplateExercise `json:"exercises"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+type TemplateExercise struct {
+	ID                    int      `json:"id"`
+	ExerciseID            *int     `json:"exercise_id"`
+	ExerciseName          string   `json:"exercise_name"`
+	OrderIndex            int      `json:"order_index"`
+	TargetSets            int      `json:"target_sets"`
+	TargetReps            *int     `json:"target_reps"`
+	TargetDurationSeconds *int     `json:"target_duration_seconds"`
+	TargetWeight          *float64 `json:"target_weight"`
+	WeightUnit            string   `json:"weight_unit"`
+	RestSeconds           *int     `json:"rest_seconds"`
+	Notes                 string   `json:"notes"`
+}
+
+// NewWorkout builds an unsaved workout for userID that is pre-filled with the
+// targets of the template.
+func (t *WorkoutTemplate) NewWorkout(userID int) *Workout {
+	workout := &Workout{
+		UserID:      userID,
+		Title:       t.Name,
+		Description: t.Description,
+		Entries:     make([]WorkoutEntry, 0, len(t.Exercises)),
+	}
+
+	for _, exercise := range t.Exercises {
+		workout.Entries = append(workout.Entries, WorkoutEntry{
+			ExerciseID:      exercise.ExerciseID,
+			ExerciseName:    exercise.ExerciseName,
+			Sets:            exercise.TargetSets,
+			Reps:            exercise.TargetReps,
+			DurationSeconds: exercise.TargetDurationSeconds,
+			Weight:          exercise.TargetWeight,
+			WeightUnit:      exercise.WeightUnit,
+			Notes:           exercise.Notes,
+			OrderIndex:      exercise.OrderIndex,
+		})
+	}
+
+	return workout
+}
+
+type PostgresTemplateStore struct {
+	db *sql.DB
+}
+
+func NewPostgresTemplateStore(db *sql.DB) *PostgresTemplateStore {
+	return &PostgresTemplateStore{
+		db: db,
+	}
+}
+
+type TemplateStore interface {
+	CreateTemplate(*WorkoutTemplate) error
+	GetTemplateByID(id int64) (*WorkoutTemplate, error)
+	ListTemplates(userID int) ([]*WorkoutTemplate, error)
+	UpdateTemplate(*WorkoutTemplate) error
+	DeleteTemplate(id int64) error
+	GetTemplateOwner(id int64) (int, error)
+}
+
+func (pg *PostgresTemplateStore) CreateTemplate(template *WorkoutTemplate) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO workout_templates (user_id, name, description)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at
+	`
+	err = tx.QueryRow(query, template.UserID, template.Name, template.Description).
+		Scan(&template.ID, &template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = insertTemplateExercises(tx, template)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (pg *PostgresTemplateStore) GetTemplateByID(id int64) (*WorkoutTemplate, error) {
+	template := &WorkoutTemplate{}
+	query := `
+		SELECT id, user_id, name, description, created_at, updated_at
+		FROM workout_templates
+		WHERE id = $1
+	`
+	err := pg.db.QueryRow(query, id).Scan(
+		&template.ID,
+		&template.UserID,
+		&template.Name,
+		&template.Description,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	exercises, err := pg.loadTemplateExercises([]int64{id})
+	if err != nil {
+		return nil, err
+	}
+	template.Exercises = exercises[template.ID]
+
+	return template, nil
+}
+
+func (pg *PostgresTemplateStore) ListTemplates(userID int) ([]*WorkoutTemplate, error) {
+	query := `
+		SELECT id, user_id, name, description, created_at, updated_at
+		FROM workout_templates
+		WHERE user_id = $1
+		ORDER BY lower(name), id
+	`
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []*WorkoutTemplate{}
+	ids := []int64{}
+	for rows.Next() {
+		template := &WorkoutTemplate{}
+		err = rows.Scan(
+			&template.ID,
+			&template.UserID,
+			&template.Name,
+			&template.Description,
+			&template.CreatedAt,
+			&template.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+		ids = append(ids, int64(template.ID))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	exercises, err := pg.loadTemplateExercises(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, template := range templates {
+		template.Exercises = exercises[template.ID]
+	}
+
+	return templates, nil
+}
+
+func (pg *PostgresTemplateStore) UpdateTemplate(template *WorkoutTemplate) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE workout_templates
+		SET name = $1, description = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+		RETURNING updated_at
+	`
+	err = tx.QueryRow(query, template.Name, template.Description, template.ID).Scan(&template.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM template_exercises WHERE template_id = $1`, template.ID)
+	if err != nil {
+		return err
+	}
+
+	err = insertTemplateExercises(tx, template)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (pg *PostgresTemplateStore) DeleteTemplate(id int64) error {
+	result, err := pg.db.Exec(`DELETE FROM workout_templates WHERE id = $1`, id)
+	if isForeignKeyViolation(err) {
+		return ErrTemplateInUse
+	}
+	if err != nil {
+		return err
+	}
+	resultRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if resultRows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (pg *PostgresTemplateStore) GetTemplateOwner(id int64) (int, error) {
+	var userID int
+	err := pg.db.QueryRow(`SELECT user_id FROM workout_templates WHERE id = $1`, id).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func insertTemplateExercises(tx *sql.Tx, template *WorkoutTemplate) error {
+	ids := []int64{}
+	for _, exercise := range template.Exercises {
+		if exercise.ExerciseID != nil {
+			ids = append(ids, int64(*exercise.ExerciseID))
+		}
+	}
+	names, err := exerciseNames(tx, template.UserID, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range template.Exercises {
+		exercise := &template.Exercises[i]
+		if exercise.ExerciseID != nil {
+			name, ok := names[*exercise.ExerciseID]
+			if !ok {
+				return ErrUnknownExercise
+			}
+			if exercise.ExerciseName == "" {
+				exercise.ExerciseName = name
+			}
+		}
+
+		if !validWeightUnit(exercise.WeightUnit) {
+			return ErrUnknownWeightUnit
+		}
+		exercise.TargetWeight = convertWeight(exercise.TargetWeight, exercise.WeightUnit, fitness.UnitKilogram, storedWeightPlaces)
+		exercise.WeightUnit = fitness.UnitKilogram
+
+		query := `
+			INSERT INTO template_exercises (template_id, exercise_id, exercise_name, order_index, target_sets,
+				target_reps, target_duration_seconds, target_weight, rest_seconds, notes)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			RETURNING id
+		`
+		err = tx.QueryRow(query,
+			template.ID,
+			exercise.ExerciseID,
+			exercise.ExerciseName,
+			exercise.OrderIndex,
+			exercise.TargetSets,
+			exercise.TargetReps,
+			exercise.TargetDurationSeconds,
+			exercise.TargetWeight,
+			exercise.RestSeconds,
+			exercise.Notes).
+			Scan(&exercise.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadTemplateExercises fetches the exercises of every given template, keyed
+// by template id.
+func (pg *PostgresTemplateStore) loadTemplateExercises(templateIDs []int64) (map[int][]TemplateExercise, error) {
+	exercises := make(map[int][]TemplateExercise, len(templateIDs))
+	if len(templateIDs) == 0 {
+		return exercises, nil
+	}
+
+	query := `
+		SELECT template_id, id, exercise_id, exercise_name, order_index, target_sets,
+			target_reps, target_duration_seconds, target_weight, rest_seconds, notes
+		FROM template_exercises
+		WHERE template_id = ANY($1)
+		ORDER BY template_id, order_index, id
+	`
+	rows, err := pg.db.Query(query, templateIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var templateID int
+		exercise := TemplateExercise{WeightUnit: fitness.UnitKilogram}
+		err = rows.Scan(
+			&templateID,
+			&exercise.ID,
+			&exercise.ExerciseID,
+			&exercise.ExerciseName,
+			&exercise.OrderIndex,
+			&exercise.TargetSets,
+			&exercise.TargetReps,
+			&exercise.TargetDurationSeconds,
+			&exercise.TargetWeight,
+			&exercise.RestSeconds,
+			&exercise.Notes,
+		)
+		if err != nil {
+			return nil, err
+		}
+		exercises[templateID] = append(exercises[templateID], exercise)
+	}
+
+	return exercises, rows.Err()
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTemplateStore(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	store := NewPostgresTemplateStore(db)
+	user := createTestUser(t, db, "template_owner")
+
+	template := &WorkoutTemplate{
+		UserID: user.ID,
+		Name:   "push day",
+		Exercises: []TemplateExercise{
+			{ExerciseName: "bench press", OrderIndex: 1, TargetSets: 3, TargetReps: createIntPtr(8), TargetWeight: createFloatPtr(135), WeightUnit: "lb"},
+			{ExerciseName: "dips", OrderIndex: 2, TargetSets: 3, TargetReps: createIntPtr(12), WeightUnit: "kg"},
+		},
+	}
+	require.NoError(t, store.CreateTemplate(template))
+	assert.NotZero(t, template.ID)
+
+	fetched, err := store.GetTemplateByID(int64(template.ID))
+	require.NoError(t, err)
+	require.Len(t, fetched.Exercises, 2)
+	assert.Equal(t, "bench press", fetched.Exercises[0].ExerciseName)
+	assert.Equal(t, "kg", fetched.Exercises[0].WeightUnit)
+	assert.InDelta(t, 61.23, *fetched.Exercises[0].TargetWeight, 0.01)
+
+	owner, err := store.GetTemplateOwner(int64(template.ID))
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, owner)
+
+	missing, err := store.GetTemplateByID(-1)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	templates, err := store.ListTemplates(user.ID)
+	require.NoError(t, err)
+	require.Len(t, templates, 1)
+	assert.Len(t, templates[0].Exercises, 2)
+
+	template.Name = "push day (heavy)"
+	template.Exercises = []TemplateExercise{
+		{ExerciseName: "overhead press", OrderIndex: 1, TargetSets: 5, TargetReps: createIntPtr(5), WeightUnit: "kg"},
+	}
+	require.NoError(t, store.UpdateTemplate(template))
+	fetched, err = store.GetTemplateByID(int64(template.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "push day (heavy)", fetched.Name)
+	require.Len(t, fetched.Exercises, 1)
+	assert.Equal(t, "overhead press", fetched.Exercises[0].ExerciseName)
+
+	unknownID := -1
+	invalid := &WorkoutTemplate{
+		UserID:    user.ID,
+		Name:      "unknown exercise",
+		Exercises: []TemplateExercise{{ExerciseID: &unknownID, OrderIndex: 1, TargetSets: 1, WeightUnit: "kg"}},
+	}
+	assert.ErrorIs(t, store.CreateTemplate(invalid), ErrUnknownExercise)
+
+	invalid.Exercises = []TemplateExercise{{ExerciseName: "curl", OrderIndex: 1, TargetSets: 1, WeightUnit: "stone"}}
+	assert.ErrorIs(t, store.CreateTemplate(invalid), ErrUnknownWeightUnit)
+
+	program := &Program{
+		UserID:        user.ID,
+		Name:          "strength block",
+		DurationWeeks: 1,
+		Days:          []ProgramDay{{WeekNumber: 1, DayNumber: 1, TemplateID: template.ID}},
+	}
+	require.NoError(t, NewPostgresProgramStore(db).CreateProgram(program))
+	assert.ErrorIs(t, store.DeleteTemplate(int64(template.ID)), ErrTemplateInUse)
+
+	unused := &WorkoutTemplate{UserID: user.ID, Name: "pull day"}
+	require.NoError(t, store.CreateTemplate(unused))
+	require.NoError(t, store.DeleteTemplate(int64(unused.ID)))
+	assert.ErrorIs(t, store.DeleteTemplate(int64(unused.ID)), sql.ErrNoRows)
+}
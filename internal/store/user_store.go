@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"errors"
+	"github.com/helmigandi/go-workout-api/internal/fitness"
 	"golang.org/x/crypto/bcrypt"
 	"time"
 )
@@ -14,6 +15,7 @@ type User struct {
 	Email        string    `json:"email"`
 	PasswordHash password  `json:"-"`
 	Bio          string    `json:"bio"`
+	WeightUnit   string    `json:"weight_unit"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -72,10 +74,14 @@ type UserStore interface {
 }
 
 func (pg *PostgresUserStore) CreateUser(user *User) error {
-	query := `INSERT INTO users (username, email, password_hash, bio) VALUES ($1, $2, $3, $4) 
+	if user.WeightUnit == "" {
+		user.WeightUnit = fitness.UnitKilogram
+	}
+
+	query := `INSERT INTO users (username, email, password_hash, bio, weight_unit) VALUES ($1, $2, $3, $4, $5) 
 		RETURNING id, created_at, updated_at, updated_at`
 
-	err := pg.db.QueryRow(query, user.Username, user.Email, user.PasswordHash.hash, user.Bio).
+	err := pg.db.QueryRow(query, user.Username, user.Email, user.PasswordHash.hash, user.Bio, user.WeightUnit).
 		Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.UpdatedAt)
 	if err != nil {
 		return err
@@ -89,13 +95,14 @@ func (pg *PostgresUserStore) GetUserByUsername(username string) (*User, error) {
 		PasswordHash: password{},
 	}
 
-	query := `SELECT id, username, email, password_hash, bio, created_at, updated_at FROM users WHERE username = $1`
+	query := `SELECT id, username, email, password_hash, bio, weight_unit, created_at, updated_at FROM users WHERE username = $1`
 	err := pg.db.QueryRow(query, username).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash.hash,
 		&user.Bio,
+		&user.WeightUnit,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -111,9 +118,9 @@ func (pg *PostgresUserStore) GetUserByUsername(username string) (*User, error) {
 }
 
 func (pg *PostgresUserStore) UpdateUser(user *User) error {
-	query := `UPDATE users SET username = $1, email = $2, bio = $3, updated_at = CURRENT_TIMESTAMP 
-             WHERE id = $4 RETURNING updated_at`
-	result, err := pg.db.Exec(query, user.Username, user.Email, user.Bio, user.ID)
+	query := `UPDATE users SET username = $1, email = $2, bio = $3, weight_unit = $4, updated_at = CURRENT_TIMESTAMP 
+             WHERE id = $5 RETURNING updated_at`
+	result, err := pg.db.Exec(query, user.Username, user.Email, user.Bio, user.WeightUnit, user.ID)
 	if err != nil {
 		return err
 	}
@@ -132,7 +139,7 @@ func (pg *PostgresUserStore) UpdateUser(user *User) error {
 func (pg *PostgresUserStore) GetUserToken(scope, tokenPlainText string) (*User, error) {
 	tokenHash := sha256.Sum256([]byte(tokenPlainText))
 
-	query := `SELECT u.id, u.username, u.email, u.password_hash, u.bio, u.created_at, u.updated_at FROM users u
+	query := `SELECT u.id, u.username, u.email, u.password_hash, u.bio, u.weight_unit, u.created_at, u.updated_at FROM users u
 		JOIN tokens t ON t.user_id = u.id
 		WHERE t.hash = $1 AND t.scope = $2 AND t.expiry > $3
 	`
@@ -141,10 +148,11 @@ func (pg *PostgresUserStore) GetUserToken(scope, tokenPlainText string) (*User,
 
 	err := pg.db.QueryRow(query, tokenHash[:], scope, time.Now()).Scan(
 		&user.ID,
-		&user.Email,
 		&user.Username,
+		&user.Email,
 		&user.PasswordHash.hash,
 		&user.Bio,
+		&user.WeightUnit,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
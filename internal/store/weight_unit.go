@@ -0,0 +1,113 @@
+package store
+
+import (
+	"errors"
+	"github.com/helmigandi/go-workout-api/internal/fitness"
+	"slices"
+)
+
+// Weights are stored in kilograms. Values that carry a WeightUnit are
+// expressed in that unit, an empty unit meaning kilograms.
+
+var ErrUnknownWeightUnit = errors.New("weight_unit must be kg or lb")
+
+// storedWeightPlaces matches the scale of the weight columns. Converted
+// weights shown to clients are rounded to displayWeightPlaces.
+const (
+	storedWeightPlaces  = 3
+	displayWeightPlaces = 2
+)
+
+func validWeightUnit(unit string) bool {
+	return unit == "" || slices.Contains(fitness.WeightUnits, unit)
+}
+
+func convertWeight(weight *float64, from, to string, places int) *float64 {
+	if weight == nil || unitOrDefault(from) == unitOrDefault(to) {
+		return weight
+	}
+	converted := roundTo(fitness.ConvertWeight(*weight, from, to), places)
+	return &converted
+}
+
+func convertAmount(amount float64, to string) float64 {
+	if unitOrDefault(to) == fitness.UnitKilogram {
+		return amount
+	}
+	return roundTo(fitness.ConvertWeight(amount, fitness.UnitKilogram, to), displayWeightPlaces)
+}
+
+func unitOrDefault(unit string) string {
+	if unit == "" {
+		return fitness.UnitKilogram
+	}
+	return unit
+}
+
+// convertWeights expresses the entry weight and every set weight in unit.
+func (e *WorkoutEntry) convertWeights(unit string, places int) {
+	e.Weight = convertWeight(e.Weight, e.WeightUnit, unit, places)
+	for i := range e.SetDetails {
+		e.SetDetails[i].Weight = convertWeight(e.SetDetails[i].Weight, e.WeightUnit, unit, places)
+	}
+	e.WeightUnit = unit
+}
+
+// canonicalizeWeights converts the entry to kilograms before it is stored.
+// Entries that do not say otherwise were logged in the unit they are sent in.
+func (e *WorkoutEntry) canonicalizeWeights() error {
+	if !validWeightUnit(e.WeightUnit) || !validWeightUnit(e.LoggedWeightUnit) {
+		return ErrUnknownWeightUnit
+	}
+	if e.LoggedWeightUnit == "" {
+		e.LoggedWeightUnit = unitOrDefault(e.WeightUnit)
+	}
+	e.convertWeights(fitness.UnitKilogram, storedWeightPlaces)
+	return nil
+}
+
+// ConvertWeights expresses every weight of the workout in unit.
+func (w *Workout) ConvertWeights(unit string) {
+	for i := range w.Entries {
+		w.Entries[i].convertWeights(unit, displayWeightPlaces)
+	}
+}
+
+func (t *WorkoutTemplate) ConvertWeights(unit string) {
+	for i := range t.Exercises {
+		exercise := &t.Exercises[i]
+		exercise.TargetWeight = convertWeight(exercise.TargetWeight, exercise.WeightUnit, unit, displayWeightPlaces)
+		exercise.WeightUnit = unit
+	}
+}
+
+// ConvertWeights converts the weight of the record and, for weight based
+// records, its value. Records are always read in kilograms.
+func (p *PersonalRecord) ConvertWeights(unit string) {
+	p.Weight = convertWeight(p.Weight, p.WeightUnit, unit, displayWeightPlaces)
+	if p.RecordType == RecordMaxWeight || p.RecordType == RecordEstimated1RM {
+		p.Value = *convertWeight(&p.Value, p.WeightUnit, unit, displayWeightPlaces)
+	}
+	p.WeightUnit = unit
+}
+
+// ConvertProgressWeights converts a kilogram based progress series to unit.
+func ConvertProgressWeights(points []ProgressPoint, unit string) {
+	for i := range points {
+		point := &points[i]
+		point.Volume = convertAmount(point.Volume, unit)
+		point.MaxWeight = convertWeight(point.MaxWeight, fitness.UnitKilogram, unit, displayWeightPlaces)
+		point.EstimatedOneRepMax = convertWeight(point.EstimatedOneRepMax, fitness.UnitKilogram, unit, displayWeightPlaces)
+	}
+}
+
+// ConvertWeights converts the lifted volume of the summary to unit. The
+// percentage changes are unit independent.
+func (s *TrainingSummary) ConvertWeights(unit string) {
+	s.Current.Volume = convertAmount(s.Current.Volume, unit)
+	s.Previous.Volume = convertAmount(s.Previous.Volume, unit)
+	s.Deltas.Volume.Change = convertAmount(s.Deltas.Volume.Change, unit)
+	for i := range s.TopExercises {
+		s.TopExercises[i].Volume = convertAmount(s.TopExercises[i].Volume, unit)
+	}
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCanonicalizeWeights(t *testing.T) {
+	entry := WorkoutEntry{
+		Weight:     createFloatPtr(135),
+		WeightUnit: "lb",
+		SetDetails: []WorkoutSet{
+			{Reps: createIntPtr(5), Weight: createFloatPtr(135)},
+			{Reps: createIntPtr(5)},
+		},
+	}
+
+	require.NoError(t, entry.canonicalizeWeights())
+	assert.Equal(t, "kg", entry.WeightUnit)
+	assert.Equal(t, "lb", entry.LoggedWeightUnit)
+	assert.Equal(t, 61.235, *entry.Weight)
+	assert.Equal(t, 61.235, *entry.SetDetails[0].Weight)
+	assert.Nil(t, entry.SetDetails[1].Weight)
+
+	workout := Workout{Entries: []WorkoutEntry{entry}}
+	workout.ConvertWeights("lb")
+	assert.Equal(t, "lb", workout.Entries[0].WeightUnit)
+	assert.Equal(t, 135.0, *workout.Entries[0].Weight)
+
+	entry = WorkoutEntry{Weight: createFloatPtr(100), WeightUnit: "stone"}
+	assert.ErrorIs(t, entry.canonicalizeWeights(), ErrUnknownWeightUnit)
+}
+
+func TestPersonalRecordConvertWeights(t *testing.T) {
+	record := PersonalRecord{RecordType: RecordMaxRepsAtWeight, Value: 8, Weight: createFloatPtr(100), WeightUnit: "kg"}
+	record.ConvertWeights("lb")
+	assert.Equal(t, 8.0, record.Value)
+	assert.Equal(t, 220.46, *record.Weight)
+
+	record = PersonalRecord{RecordType: RecordMaxWeight, Value: 100, WeightUnit: "kg"}
+	record.ConvertWeights("lb")
+	assert.Equal(t, 220.46, record.Value)
+	assert.Equal(t, "lb", record.WeightUnit)
+}
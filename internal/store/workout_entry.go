@@ -0,0 +1,320 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	EntryTypeStrength = "strength"
+	EntryTypeCardio   = "cardio"
+)
+
+var EntryTypes = []string{EntryTypeStrength, EntryTypeCardio}
+
+var DistanceUnits = []string{"km", "mi"}
+
+var ErrInvalidEntry = errors.New("invalid workout entry")
+
+func invalidEntry(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidEntry, fmt.Sprintf(format, args...))
+}
+
+// Validate checks the entry against the rules of its type. Strength entries
+// count either reps or a duration, cardio entries always need a duration and
+// may add a distance and elevation gain.
+func (e *WorkoutEntry) Validate() error {
+	entryType := e.EntryType
+	if entryType == "" {
+		entryType = EntryTypeStrength
+	}
+	if !slices.Contains(EntryTypes, entryType) {
+		return invalidEntry("entry_type must be strength or cardio")
+	}
+
+	for _, set := range e.SetDetails {
+		if set.Reps == nil && set.DurationSeconds == nil {
+			return invalidEntry("every set needs reps or duration_seconds")
+		}
+		if entryType == EntryTypeCardio && (set.DurationSeconds == nil || set.Reps != nil) {
+			return invalidEntry("cardio sets need duration_seconds and no reps")
+		}
+		if set.RPE != nil && (*set.RPE < 1 || *set.RPE > 10) {
+			return invalidEntry("rpe must be between 1 and 10")
+		}
+		if set.RIR != nil && *set.RIR < 0 {
+			return invalidEntry("rir must not be negative")
+		}
+	}
+
+	if len(e.SetDetails) == 0 {
+		switch entryType {
+		case EntryTypeStrength:
+			if (e.Reps == nil) == (e.DurationSeconds == nil) {
+				return invalidEntry("strength entries need either reps or duration_seconds")
+			}
+		case EntryTypeCardio:
+			if e.DurationSeconds == nil || e.Reps != nil {
+				return invalidEntry("cardio entries need duration_seconds and no reps")
+			}
+		}
+	}
+
+	if e.DurationSeconds != nil && *e.DurationSeconds <= 0 {
+		return invalidEntry("duration_seconds must be positive")
+	}
+
+	if entryType == EntryTypeStrength && (e.Distance != nil || e.ElevationGainMeters != nil) {
+		return invalidEntry("distance and elevation_gain_meters are only allowed on cardio entries")
+	}
+
+	if e.Distance != nil {
+		if *e.Distance <= 0 {
+			return invalidEntry("distance must be positive")
+		}
+		if !slices.Contains(DistanceUnits, e.DistanceUnit) {
+			return invalidEntry("distance_unit must be km or mi")
+		}
+	} else if e.DistanceUnit != "" {
+		return invalidEntry("distance_unit requires a distance")
+	}
+
+	if e.ElevationGainMeters != nil && *e.ElevationGainMeters < 0 {
+		return invalidEntry("elevation_gain_meters must not be negative")
+	}
+
+	for _, heartRate := range []*int{e.AvgHeartRate, e.MaxHeartRate} {
+		if heartRate != nil && (*heartRate < 20 || *heartRate > 250) {
+			return invalidEntry("heart rates must be between 20 and 250")
+		}
+	}
+	if e.AvgHeartRate != nil && e.MaxHeartRate != nil && *e.AvgHeartRate > *e.MaxHeartRate {
+		return invalidEntry("avg_heart_rate must not exceed max_heart_rate")
+	}
+
+	return nil
+}
+
+// derivePace fills in the pace (seconds per distance unit) and speed
+// (distance units per hour) of entries that cover a distance.
+func (e *WorkoutEntry) derivePace() {
+	e.Pace, e.Speed = nil, nil
+	if e.Distance == nil || *e.Distance <= 0 || e.DurationSeconds == nil || *e.DurationSeconds <= 0 {
+		return
+	}
+
+	seconds := float64(*e.DurationSeconds)
+	pace := roundTo(seconds / *e.Distance, 1)
+	speed := roundTo(*e.Distance/seconds*3600, 2)
+	e.Pace, e.Speed = &pace, &speed
+}
+
+// CreateEntry adds a single entry to the workout. The entries at or after its
+// order_index move back one place. Entry groups are not touched, so a group
+// label must refer to one of the groups of the workout.
+//
+// Like the other entry changes it returns ErrEditConflict unless the stored
+// workout is still at workout.Version, sets the new version on workout and
+// records the change as made by actorID.
+func (pg *PostgresWorkoutStore) CreateEntry(workout *Workout, entry *WorkoutEntry, actorID int) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockWorkoutVersion(tx, workout)
+	if err != nil {
+		return err
+	}
+
+	err = resolveEntryExercise(tx, workout.UserID, entry)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE workout_entries SET order_index = order_index + 1 WHERE workout_id = $1 AND order_index >= $2`
+	_, err = tx.Exec(query, workout.ID, entry.OrderIndex)
+	if err != nil {
+		return err
+	}
+
+	err = insertEntryBatch(tx, workout.ID, []*WorkoutEntry{entry})
+	if err != nil {
+		return err
+	}
+
+	err = touchWorkout(tx, workout, before, actorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateEntry overwrites a single entry of the workout in place, keeping its
+// id. The logged sets of the entry are replaced.
+func (pg *PostgresWorkoutStore) UpdateEntry(workout *Workout, entry *WorkoutEntry, actorID int) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockWorkoutVersion(tx, workout)
+	if err != nil {
+		return err
+	}
+
+	err = resolveEntryExercise(tx, workout.UserID, entry)
+	if err != nil {
+		return err
+	}
+
+	err = updateEntryBatch(tx, workout.ID, []*WorkoutEntry{entry})
+	if err != nil {
+		return err
+	}
+
+	err = touchWorkout(tx, workout, before, actorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteEntry removes a single entry from the workout. A group that is left
+// with a single entry is dissolved.
+func (pg *PostgresWorkoutStore) DeleteEntry(workout *Workout, entryID int64, actorID int) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockWorkoutVersion(tx, workout)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM workout_entries WHERE id = $1 AND workout_id = $2`, entryID, workout.ID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	err = dissolveSmallGroups(tx, int64(workout.ID))
+	if err != nil {
+		return err
+	}
+
+	err = touchWorkout(tx, workout, before, actorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReorderEntries numbers the entries of the workout from 1 in the order of
+// entryIDs, which has to list every entry of the workout exactly once.
+func (pg *PostgresWorkoutStore) ReorderEntries(workout *Workout, entryIDs []int64, actorID int) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockWorkoutVersion(tx, workout)
+	if err != nil {
+		return err
+	}
+
+	var entryCount int
+	err = tx.QueryRow(`SELECT count(*) FROM workout_entries WHERE workout_id = $1`, workout.ID).Scan(&entryCount)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE workout_entries e
+		SET order_index = o.position
+		FROM unnest($2::bigint[]) WITH ORDINALITY AS o(id, position)
+		WHERE e.id = o.id AND e.workout_id = $1
+	`
+	result, err := tx.Exec(query, workout.ID, entryIDs)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(rowsAffected) != entryCount || len(entryIDs) != entryCount {
+		return invalidEntry("entry_ids must list every entry of the workout once")
+	}
+
+	err = touchWorkout(tx, workout, before, actorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// resolveEntryExercise is resolveExercises for a single entry.
+func resolveEntryExercise(tx *sql.Tx, userID int, entry *WorkoutEntry) error {
+	entries := []WorkoutEntry{*entry}
+	err := resolveExercises(tx, userID, entries)
+	if err != nil {
+		return err
+	}
+	*entry = entries[0]
+	return nil
+}
+
+// lockWorkoutVersion locks the workout for the rest of tx like
+// snapshotForUpdate. It returns sql.ErrNoRows when the workout is gone and
+// ErrEditConflict when it is no longer at workout.Version.
+func lockWorkoutVersion(tx *sql.Tx, workout *Workout) (*Workout, error) {
+	before, err := snapshotForUpdate(tx, int64(workout.ID))
+	if err != nil {
+		return nil, err
+	}
+	if before == nil || before.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+	if before.Version != workout.Version {
+		return nil, ErrEditConflict
+	}
+	return before, nil
+}
+
+// touchWorkout records that actorID changed the entries or tags of the
+// workout, which started out as before. The workout gets the next version,
+// provided it is still at workout.Version, and a revision.
+func touchWorkout(tx *sql.Tx, workout *Workout, before *Workout, actorID int) error {
+	query := `
+		UPDATE workouts
+		SET updated_at = CURRENT_TIMESTAMP, version = version + 1
+		WHERE id = $1 AND version = $2
+		RETURNING version, updated_at
+	`
+	err := tx.QueryRow(query, workout.ID, workout.Version).Scan(&workout.Version, &workout.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrEditConflict
+	}
+	if err != nil {
+		return err
+	}
+	return recordRevision(tx, int64(workout.ID), actorID, RevisionUpdate, before)
+}
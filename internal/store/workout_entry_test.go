@@ -0,0 +1,209 @@
+package store
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWorkoutEntryValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   WorkoutEntry
+		wantErr bool
+	}{
+		{
+			name:  "strength with reps",
+			entry: WorkoutEntry{Sets: 3, Reps: createIntPtr(10)},
+		},
+		{
+			name:    "strength with reps and duration",
+			entry:   WorkoutEntry{Sets: 3, Reps: createIntPtr(10), DurationSeconds: createIntPtr(30)},
+			wantErr: true,
+		},
+		{
+			name:    "strength with distance",
+			entry:   WorkoutEntry{EntryType: EntryTypeStrength, Reps: createIntPtr(10), Distance: createFloatPtr(1), DistanceUnit: "km"},
+			wantErr: true,
+		},
+		{
+			name: "run with distance and heart rate",
+			entry: WorkoutEntry{
+				EntryType:       EntryTypeCardio,
+				DurationSeconds: createIntPtr(1800),
+				Distance:        createFloatPtr(5),
+				DistanceUnit:    "km",
+				AvgHeartRate:    createIntPtr(150),
+				MaxHeartRate:    createIntPtr(172),
+			},
+		},
+		{
+			name:    "cardio without duration",
+			entry:   WorkoutEntry{EntryType: EntryTypeCardio, Distance: createFloatPtr(5), DistanceUnit: "km"},
+			wantErr: true,
+		},
+		{
+			name:    "distance without unit",
+			entry:   WorkoutEntry{EntryType: EntryTypeCardio, DurationSeconds: createIntPtr(1800), Distance: createFloatPtr(5)},
+			wantErr: true,
+		},
+		{
+			name: "average above max heart rate",
+			entry: WorkoutEntry{
+				EntryType:       EntryTypeCardio,
+				DurationSeconds: createIntPtr(1800),
+				AvgHeartRate:    createIntPtr(180),
+				MaxHeartRate:    createIntPtr(170),
+			},
+			wantErr: true,
+		},
+		{
+			name: "sets with rpe and rir",
+			entry: WorkoutEntry{SetDetails: []WorkoutSet{
+				{Reps: createIntPtr(5), RPE: createFloatPtr(8.5), RIR: createIntPtr(2)},
+			}},
+		},
+		{
+			name:    "rpe above 10",
+			entry:   WorkoutEntry{SetDetails: []WorkoutSet{{Reps: createIntPtr(5), RPE: createFloatPtr(11)}}},
+			wantErr: true,
+		},
+		{
+			name:    "rpe below 1",
+			entry:   WorkoutEntry{SetDetails: []WorkoutSet{{Reps: createIntPtr(5), RPE: createFloatPtr(0)}}},
+			wantErr: true,
+		},
+		{
+			name:    "negative rir",
+			entry:   WorkoutEntry{SetDetails: []WorkoutSet{{Reps: createIntPtr(5), RIR: createIntPtr(-1)}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			entry:   WorkoutEntry{EntryType: "swim", DurationSeconds: createIntPtr(1800)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.entry.Validate()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidEntry)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDerivePace(t *testing.T) {
+	entry := WorkoutEntry{EntryType: EntryTypeCardio, DurationSeconds: createIntPtr(1500), Distance: createFloatPtr(5), DistanceUnit: "km"}
+	entry.derivePace()
+	assert.Equal(t, 300.0, *entry.Pace)
+	assert.Equal(t, 12.0, *entry.Speed)
+
+	entry.Distance = nil
+	entry.derivePace()
+	assert.Nil(t, entry.Pace)
+	assert.Nil(t, entry.Speed)
+}
+
+func TestWorkoutEntryChanges(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	store := NewPostgresWorkoutStore(db)
+	user := createTestUser(t, db, "entry_user")
+
+	workout, err := store.CreateWorkout(&Workout{
+		UserID: user.ID,
+		Title:  "arm day",
+		Groups: []WorkoutGroup{{Label: "A", GroupType: GroupTypeSuperset, Rounds: 3}},
+		Entries: []WorkoutEntry{
+			{ExerciseName: "Barbell Curl", Sets: 3, Reps: createIntPtr(10), OrderIndex: 1, GroupLabel: "A"},
+			{ExerciseName: "Skull Crusher", Sets: 3, Reps: createIntPtr(10), OrderIndex: 2, GroupLabel: "A"},
+			{ExerciseName: "Dips", Sets: 3, Reps: createIntPtr(12), OrderIndex: 3},
+		},
+	})
+	require.NoError(t, err)
+
+	reload := func() *Workout {
+		saved, err := store.GetWorkoutByID(int64(workout.ID))
+		require.NoError(t, err)
+		return saved
+	}
+	names := func(workout *Workout) []string {
+		names := []string{}
+		for _, entry := range workout.Entries {
+			names = append(names, entry.ExerciseName)
+		}
+		return names
+	}
+
+	// an entry placed at a taken order_index moves the later entries back
+	pushups := WorkoutEntry{ExerciseName: "Push Up", Sets: 2, Reps: createIntPtr(20), OrderIndex: 3}
+	require.NoError(t, store.CreateEntry(workout, &pushups, user.ID))
+	assert.NotZero(t, pushups.ID)
+	saved := reload()
+	assert.Equal(t, []string{"Barbell Curl", "Skull Crusher", "Push Up", "Dips"}, names(saved))
+	assert.Equal(t, 4, saved.Entries[3].OrderIndex)
+	assert.Equal(t, 2, saved.Version)
+	assert.Equal(t, 2, workout.Version)
+
+	// a change based on an older version is rejected
+	stale := *workout
+	stale.Version = 1
+	assert.ErrorIs(t, store.CreateEntry(&stale, &WorkoutEntry{ExerciseName: "Plank", Sets: 1, DurationSeconds: createIntPtr(60)}, user.ID), ErrEditConflict)
+	assert.ErrorIs(t, store.DeleteEntry(&stale, int64(pushups.ID), user.ID), ErrEditConflict)
+
+	pushups.Reps = createIntPtr(25)
+	pushups.SetDetails = nil
+	require.NoError(t, store.UpdateEntry(workout, &pushups, user.ID))
+	saved = reload()
+	assert.Equal(t, 25, *saved.Entries[2].Reps)
+	assert.Equal(t, pushups.ID, saved.Entries[2].ID)
+	assert.Equal(t, 3, saved.Version)
+
+	ids := []int64{}
+	for _, entry := range saved.Entries {
+		ids = append(ids, int64(entry.ID))
+	}
+	require.NoError(t, store.ReorderEntries(workout, []int64{ids[3], ids[0], ids[1], ids[2]}, user.ID))
+	saved = reload()
+	assert.Equal(t, []string{"Dips", "Barbell Curl", "Skull Crusher", "Push Up"}, names(saved))
+	assert.ErrorIs(t, store.ReorderEntries(workout, ids[:2], user.ID), ErrInvalidEntry)
+
+	// deleting from a superset of two leaves a plain entry behind
+	require.NoError(t, store.DeleteEntry(workout, ids[0], user.ID))
+	saved = reload()
+	assert.Equal(t, []string{"Dips", "Skull Crusher", "Push Up"}, names(saved))
+	assert.Empty(t, saved.Groups)
+	assert.Empty(t, saved.Entries[1].GroupLabel)
+	assert.Equal(t, 5, saved.Version)
+
+	assert.ErrorIs(t, store.DeleteEntry(workout, ids[0], user.ID), sql.ErrNoRows)
+}
+
+func TestDissolveSmallGroups(t *testing.T) {
+	workout := &Workout{
+		Groups: []WorkoutGroup{
+			{Label: "A", GroupType: GroupTypeSuperset, Rounds: 1},
+			{Label: "B", GroupType: GroupTypeCircuit, Rounds: 1},
+		},
+		Entries: []WorkoutEntry{
+			{OrderIndex: 1, GroupLabel: "A"},
+			{OrderIndex: 2, GroupLabel: "B"},
+			{OrderIndex: 3, GroupLabel: "B"},
+			{OrderIndex: 4},
+		},
+	}
+
+	workout.DissolveSmallGroups()
+	require.Len(t, workout.Groups, 1)
+	assert.Equal(t, "B", workout.Groups[0].Label)
+	assert.Empty(t, workout.Entries[0].GroupLabel)
+	assert.Equal(t, "B", workout.Entries[1].GroupLabel)
+	assert.NoError(t, workout.ValidateGroups())
+}
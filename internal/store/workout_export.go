@@ -0,0 +1,95 @@
+package store
+
+import (
+	"time"
+)
+
+// exportBatchSize is the number of workouts ExportWorkouts loads at a time.
+const exportBatchSize = 100
+
+type ExportFilter struct {
+	UserID int
+	// From and To bound performed_at, To being exclusive.
+	From *time.Time
+	To   *time.Time
+}
+
+// ExportWorkouts calls fn with every workout of the user in the range, oldest
+// first, and stops at the first error fn returns. Workouts are loaded in
+// batches, so exporting does not hold all of them in memory. Workouts in the
+// trash are left out.
+func (pg *PostgresWorkoutStore) ExportWorkouts(filter ExportFilter, fn func(*Workout) error) error {
+	var afterTime *time.Time
+	afterID := 0
+	for {
+		workouts, err := pg.exportBatch(filter, afterTime, afterID)
+		if err != nil {
+			return err
+		}
+
+		for _, workout := range workouts {
+			err = fn(workout)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(workouts) < exportBatchSize {
+			return nil
+		}
+		last := workouts[len(workouts)-1]
+		afterTime, afterID = &last.PerformedAt, last.ID
+	}
+}
+
+// exportBatch loads the workouts following (afterTime, afterID) in the order
+// of ExportWorkouts.
+func (pg *PostgresWorkoutStore) exportBatch(filter ExportFilter, afterTime *time.Time, afterID int) ([]*Workout, error) {
+	query := `
+		SELECT id, user_id, title, description, duration_minutes, calories_burned,
+			version, performed_at, created_at, updated_at
+		FROM workouts
+		WHERE user_id = $1 AND deleted_at IS NULL
+		AND ($2::timestamptz IS NULL OR performed_at >= $2)
+		AND ($3::timestamptz IS NULL OR performed_at < $3)
+		AND ($4::timestamptz IS NULL OR (performed_at, id) > ($4, $5))
+		ORDER BY performed_at, id
+		LIMIT $6
+	`
+	rows, err := pg.db.Query(query, filter.UserID, filter.From, filter.To, afterTime, afterID, exportBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workouts := make([]*Workout, 0, exportBatchSize)
+	for rows.Next() {
+		workout := &Workout{}
+		err = rows.Scan(
+			&workout.ID,
+			&workout.UserID,
+			&workout.Title,
+			&workout.Description,
+			&workout.DurationMinutes,
+			&workout.CaloriesBurned,
+			&workout.Version,
+			&workout.PerformedAt,
+			&workout.CreatedAt,
+			&workout.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		workouts = append(workouts, workout)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = loadWorkoutDetails(pg.db, workouts)
+	if err != nil {
+		return nil, err
+	}
+
+	return workouts, nil
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	GroupTypeSuperset = "superset"
+	GroupTypeCircuit  = "circuit"
+)
+
+var GroupTypes = []string{GroupTypeSuperset, GroupTypeCircuit}
+
+var ErrInvalidGroup = errors.New("invalid entry group")
+
+// WorkoutGroup performs the entries that carry its label back to back, for
+// the given number of rounds with RestSeconds of rest between rounds.
+type WorkoutGroup struct {
+	Label       string `json:"label"`
+	GroupType   string `json:"group_type"`
+	Rounds      int    `json:"rounds"`
+	RestSeconds *int   `json:"rest_seconds"`
+}
+
+func invalidGroup(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidGroup, fmt.Sprintf(format, args...))
+}
+
+// ValidateGroups checks that every group has at least two entries which
+// follow each other in order_index order. Grouped entries need an order_index
+// of their own, ungrouped entries may share one, as clients that do not order
+// entries send them all with 0.
+func (w *Workout) ValidateGroups() error {
+	groups := make(map[string]bool, len(w.Groups))
+	for _, group := range w.Groups {
+		if group.Label == "" || len(group.Label) > 50 {
+			return invalidGroup("label must be between 1 and 50 characters")
+		}
+		if groups[group.Label] {
+			return invalidGroup("label %q is used twice", group.Label)
+		}
+		if !slices.Contains(GroupTypes, group.GroupType) {
+			return invalidGroup("group_type must be superset or circuit")
+		}
+		if group.Rounds < 1 {
+			return invalidGroup("rounds must be at least 1")
+		}
+		if group.RestSeconds != nil && *group.RestSeconds < 0 {
+			return invalidGroup("rest_seconds must not be negative")
+		}
+		groups[group.Label] = true
+	}
+
+	entries := slices.Clone(w.Entries)
+	slices.SortStableFunc(entries, func(a, b WorkoutEntry) int { return a.OrderIndex - b.OrderIndex })
+
+	orderIndexes := make(map[int]int, len(entries))
+	for _, entry := range entries {
+		orderIndexes[entry.OrderIndex]++
+	}
+
+	counts := make(map[string]int, len(groups))
+	for i, entry := range entries {
+		if entry.GroupLabel == "" {
+			continue
+		}
+		if orderIndexes[entry.OrderIndex] > 1 {
+			return invalidGroup("order_index %d of a grouped entry is used twice", entry.OrderIndex)
+		}
+		if !groups[entry.GroupLabel] {
+			return invalidGroup("group_label %q does not match a group", entry.GroupLabel)
+		}
+		if counts[entry.GroupLabel] > 0 && entries[i-1].GroupLabel != entry.GroupLabel {
+			return invalidGroup("entries of group %q must be consecutive", entry.GroupLabel)
+		}
+		counts[entry.GroupLabel]++
+	}
+
+	for label := range groups {
+		if counts[label] < 2 {
+			return invalidGroup("group %q needs at least two entries", label)
+		}
+	}
+
+	return nil
+}
+
+// upsertGroups stores the groups of the workout, updating the ones whose
+// label already exists.
+func upsertGroups(tx *sql.Tx, workout *Workout) error {
+	for _, group := range workout.Groups {
+		query := `
+			INSERT INTO workout_entry_groups (workout_id, label, group_type, rounds, rest_seconds)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (workout_id, label)
+			DO UPDATE SET group_type = EXCLUDED.group_type, rounds = EXCLUDED.rounds, rest_seconds = EXCLUDED.rest_seconds
+		`
+		_, err := tx.Exec(query, workout.ID, group.Label, group.GroupType, group.Rounds, group.RestSeconds)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteStaleGroups removes the stored groups the workout no longer has.
+func deleteStaleGroups(tx *sql.Tx, workout *Workout) error {
+	labels := make([]string, len(workout.Groups))
+	for i, group := range workout.Groups {
+		labels[i] = group.Label
+	}
+
+	_, err := tx.Exec(`DELETE FROM workout_entry_groups WHERE workout_id = $1 AND NOT (label = ANY($2))`, workout.ID, labels)
+	return err
+}
+
+// dissolveSmallGroups ungroups the entries of groups that have fewer than two
+// entries left and removes those groups.
+func dissolveSmallGroups(tx *sql.Tx, workoutID int64) error {
+	query := `
+		UPDATE workout_entries
+		SET group_label = NULL
+		WHERE workout_id = $1 AND group_label IN (
+			SELECT group_label
+			FROM workout_entries
+			WHERE workout_id = $1 AND group_label IS NOT NULL
+			GROUP BY group_label
+			HAVING count(*) < 2
+		)
+	`
+	_, err := tx.Exec(query, workoutID)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		DELETE FROM workout_entry_groups g
+		WHERE g.workout_id = $1
+		AND NOT EXISTS (SELECT 1 FROM workout_entries e WHERE e.workout_id = g.workout_id AND e.group_label = g.label)
+	`
+	_, err = tx.Exec(query, workoutID)
+	return err
+}
+
+// DissolveSmallGroups does what DeleteEntry does to the stored groups for
+// the groups and entries of w.
+func (w *Workout) DissolveSmallGroups() {
+	counts := make(map[string]int, len(w.Groups))
+	for _, entry := range w.Entries {
+		counts[entry.GroupLabel]++
+	}
+
+	for i := range w.Entries {
+		if counts[w.Entries[i].GroupLabel] < 2 {
+			w.Entries[i].GroupLabel = ""
+		}
+	}
+	w.Groups = slices.DeleteFunc(w.Groups, func(group WorkoutGroup) bool { return counts[group.Label] < 2 })
+}
+
+// loadGroups fetches the entry groups of every given workout, keyed by
+// workout id.
+func loadGroups(q queryer, workoutIDs []int64) (map[int][]WorkoutGroup, error) {
+	groups := make(map[int][]WorkoutGroup, len(workoutIDs))
+	if len(workoutIDs) == 0 {
+		return groups, nil
+	}
+
+	query := `
+		SELECT workout_id, label, group_type, rounds, rest_seconds
+		FROM workout_entry_groups
+		WHERE workout_id = ANY($1)
+		ORDER BY workout_id, label
+	`
+	rows, err := q.Query(query, workoutIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workoutID int
+		var group WorkoutGroup
+		err = rows.Scan(&workoutID, &group.Label, &group.GroupType, &group.Rounds, &group.RestSeconds)
+		if err != nil {
+			return nil, err
+		}
+		groups[workoutID] = append(groups[workoutID], group)
+	}
+
+	return groups, rows.Err()
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidateGroups(t *testing.T) {
+	entry := func(orderIndex int, label string) WorkoutEntry {
+		return WorkoutEntry{ExerciseName: "Exercise", Sets: 3, Reps: createIntPtr(10), OrderIndex: orderIndex, GroupLabel: label}
+	}
+	superset := WorkoutGroup{Label: "A", GroupType: GroupTypeSuperset, Rounds: 3, RestSeconds: createIntPtr(90)}
+
+	tests := []struct {
+		name    string
+		workout Workout
+		wantErr bool
+	}{
+		{
+			name:    "no groups",
+			workout: Workout{Entries: []WorkoutEntry{entry(1, ""), entry(2, "")}},
+		},
+		{
+			name: "superset between ungrouped entries, sent out of order",
+			workout: Workout{
+				Groups:  []WorkoutGroup{superset},
+				Entries: []WorkoutEntry{entry(4, ""), entry(2, "A"), entry(1, ""), entry(3, "A")},
+			},
+		},
+		{
+			name: "group split by another entry",
+			workout: Workout{
+				Groups:  []WorkoutGroup{superset},
+				Entries: []WorkoutEntry{entry(1, "A"), entry(2, ""), entry(3, "A")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "group with a single entry",
+			workout: Workout{
+				Groups:  []WorkoutGroup{superset},
+				Entries: []WorkoutEntry{entry(1, "A"), entry(2, "")},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown label",
+			workout: Workout{Entries: []WorkoutEntry{entry(1, "B"), entry(2, "B")}},
+			wantErr: true,
+		},
+		{
+			name:    "ungrouped entries sharing an order index",
+			workout: Workout{Entries: []WorkoutEntry{entry(0, ""), entry(0, ""), entry(0, "")}},
+		},
+		{
+			name: "grouped entry sharing an order index",
+			workout: Workout{
+				Groups:  []WorkoutGroup{superset},
+				Entries: []WorkoutEntry{entry(1, "A"), entry(2, "A"), entry(2, "")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero rounds",
+			workout: Workout{
+				Groups:  []WorkoutGroup{{Label: "A", GroupType: GroupTypeCircuit}},
+				Entries: []WorkoutEntry{entry(1, "A"), entry(2, "A")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown group type",
+			workout: Workout{
+				Groups:  []WorkoutGroup{{Label: "A", GroupType: "giant", Rounds: 1}},
+				Entries: []WorkoutEntry{entry(1, "A"), entry(2, "A")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.workout.ValidateGroups()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidGroup)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
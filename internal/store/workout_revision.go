@@ -0,0 +1,187 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	RevisionCreate   = "create"
+	RevisionUpdate   = "update"
+	RevisionDelete   = "delete"
+	RevisionRestore  = "restore"
+	RevisionRollback = "rollback"
+)
+
+// WorkoutRevision records a single change to a workout or its entries, made
+// by UserID. The snapshots hold the workout with weights in kilograms, Before
+// is nil for the revision that created the workout.
+type WorkoutRevision struct {
+	ID        int       `json:"id"`
+	WorkoutID int       `json:"workout_id"`
+	Version   int       `json:"version"`
+	UserID    *int      `json:"user_id"`
+	Action    string    `json:"action"`
+	Before    *Workout  `json:"before"`
+	After     *Workout  `json:"after"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// snapshotForUpdate locks the workout for the rest of tx and returns its
+// current state, or nil when there is no such workout.
+func snapshotForUpdate(tx *sql.Tx, id int64) (*Workout, error) {
+	_, err := tx.Exec(`SELECT 1 FROM workouts WHERE id = $1 FOR UPDATE`, id)
+	if err != nil {
+		return nil, err
+	}
+	return getWorkout(tx, id)
+}
+
+// recordRevision stores the change actorID made to the workout in tx. The
+// workout started out as before.
+func recordRevision(tx *sql.Tx, workoutID int64, actorID int, action string, before *Workout) error {
+	after, err := getWorkout(tx, workoutID)
+	if err != nil {
+		return err
+	}
+	if after == nil {
+		return sql.ErrNoRows
+	}
+
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+	var beforeJSON *string
+	if before != nil {
+		data, err := json.Marshal(before)
+		if err != nil {
+			return err
+		}
+		snapshot := string(data)
+		beforeJSON = &snapshot
+	}
+
+	query := `
+		INSERT INTO workout_revisions (workout_id, version, user_id, action, before, after)
+		VALUES ($1, $2, $3, $4, $5::jsonb, $6::jsonb)
+	`
+	_, err = tx.Exec(query, workoutID, after.Version, actorID, action, beforeJSON, string(afterJSON))
+	return err
+}
+
+// ListRevisions returns the revisions of the workout, newest first.
+func (pg *PostgresWorkoutStore) ListRevisions(workoutID int64) ([]WorkoutRevision, error) {
+	query := `
+		SELECT id, workout_id, version, user_id, action, before, after, created_at
+		FROM workout_revisions
+		WHERE workout_id = $1
+		ORDER BY id DESC
+	`
+	rows, err := pg.db.Query(query, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []WorkoutRevision{}
+	for rows.Next() {
+		var revision WorkoutRevision
+		var before, after []byte
+		err = rows.Scan(
+			&revision.ID,
+			&revision.WorkoutID,
+			&revision.Version,
+			&revision.UserID,
+			&revision.Action,
+			&before,
+			&after,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if before != nil {
+			revision.Before = &Workout{}
+			err = json.Unmarshal(before, revision.Before)
+			if err != nil {
+				return nil, err
+			}
+		}
+		revision.After = &Workout{}
+		err = json.Unmarshal(after, revision.After)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// RollbackWorkout brings the workout back to the state it had after the given
+// revision, as a new revision. Like UpdateWorkout it only changes the workout
+// while it is still at version, where 0 accepts any version. It returns
+// sql.ErrNoRows when the workout has no such revision.
+func (pg *PostgresWorkoutStore) RollbackWorkout(workoutID, revisionID int64, version int, actorID int) (*Workout, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, err := snapshotForUpdate(tx, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || current.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+
+	var snapshot []byte
+	err = tx.QueryRow(`SELECT after FROM workout_revisions WHERE id = $1 AND workout_id = $2`, revisionID, workoutID).
+		Scan(&snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	workout := &Workout{}
+	err = json.Unmarshal(snapshot, workout)
+	if err != nil {
+		return nil, err
+	}
+	workout.ID = current.ID
+	workout.UserID = current.UserID
+	workout.CreatedAt = current.CreatedAt
+	workout.DeletedAt = nil
+	workout.Version = current.Version
+	if version != 0 {
+		workout.Version = version
+	}
+
+	// entries deleted since the revision come back as new entries
+	stored := make(map[int]bool, len(current.Entries))
+	for _, entry := range current.Entries {
+		stored[entry.ID] = true
+	}
+	for i := range workout.Entries {
+		if !stored[workout.Entries[i].ID] {
+			workout.Entries[i].ID = 0
+		}
+	}
+
+	err = updateWorkout(tx, workout, current, RevisionRollback, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return workout, nil
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWorkoutRevisions(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	store := NewPostgresWorkoutStore(db)
+	user := createTestUser(t, db, "revision_user")
+	coach := createTestUser(t, db, "revision_coach")
+
+	workout, err := store.CreateWorkout(&Workout{
+		UserID:  user.ID,
+		Title:   "pull day",
+		Entries: []WorkoutEntry{{ExerciseName: "Pull Ups", Sets: 3, Reps: createIntPtr(8), OrderIndex: 1}},
+	})
+	require.NoError(t, err)
+
+	workout.Title = "heavy pull day"
+	workout.Entries = []WorkoutEntry{{ExerciseName: "Deadlift", Sets: 5, Reps: createIntPtr(5), Weight: createFloatPtr(140), OrderIndex: 1}}
+	require.NoError(t, store.UpdateWorkout(workout, coach.ID))
+
+	revisions, err := store.ListRevisions(int64(workout.ID))
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, RevisionUpdate, revisions[0].Action)
+	assert.Equal(t, 2, revisions[0].Version)
+	assert.Equal(t, "pull day", revisions[0].Before.Title)
+	assert.Equal(t, "heavy pull day", revisions[0].After.Title)
+	// the revision names who made the change, not the owner
+	require.NotNil(t, revisions[0].UserID)
+	assert.Equal(t, coach.ID, *revisions[0].UserID)
+	assert.Equal(t, RevisionCreate, revisions[1].Action)
+	assert.Nil(t, revisions[1].Before)
+	require.NotNil(t, revisions[1].UserID)
+	assert.Equal(t, user.ID, *revisions[1].UserID)
+
+	_, err = store.RollbackWorkout(int64(workout.ID), int64(revisions[1].ID), 1, user.ID)
+	assert.ErrorIs(t, err, ErrEditConflict)
+
+	rolledBack, err := store.RollbackWorkout(int64(workout.ID), int64(revisions[1].ID), 0, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, rolledBack.Version)
+
+	saved, err := store.GetWorkoutByID(int64(workout.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "pull day", saved.Title)
+	require.Len(t, saved.Entries, 1)
+	assert.Equal(t, "Pull Ups", saved.Entries[0].ExerciseName)
+
+	require.NoError(t, store.DeleteWorkout(int64(workout.ID), 0, user.ID))
+	revisions, err = store.ListRevisions(int64(workout.ID))
+	require.NoError(t, err)
+	require.Len(t, revisions, 4)
+	assert.Equal(t, RevisionDelete, revisions[0].Action)
+	assert.Equal(t, RevisionRollback, revisions[1].Action)
+}
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"html"
+	"strings"
+)
+
+type SearchFilter struct {
+	UserID int
+	// Query uses the web search syntax of postgres: quoted phrases, "or" and
+	// a leading "-" to exclude a word.
+	Query string
+	Page  int
+	Limit int
+}
+
+// SearchHighlight is a snippet of a matching field with the matched words
+// wrapped in <b> tags. The text around them is HTML-escaped, so the snippet
+// can be shown as HTML. EntryID is set for the fields of an entry.
+type SearchHighlight struct {
+	Field   string `json:"field"`
+	EntryID *int   `json:"entry_id,omitempty"`
+	Snippet string `json:"snippet"`
+}
+
+type WorkoutSearchResult struct {
+	Workout    *Workout          `json:"workout"`
+	Rank       float64           `json:"rank"`
+	Highlights []SearchHighlight `json:"highlights"`
+}
+
+// ts_headline marks the matched words with control characters, which are
+// turned into tags once the text has been escaped.
+const (
+	highlightStart  = "\x02"
+	highlightStop   = "\x03"
+	headlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", MaxFragments=2, MaxWords=20, MinWords=5"
+)
+
+var highlightTags = strings.NewReplacer(highlightStart, "<b>", highlightStop, "</b>")
+
+// highlightSnippet turns a headline of ts_headline into a snippet.
+func highlightSnippet(headline string) string {
+	return highlightTags.Replace(html.EscapeString(headline))
+}
+
+// SearchWorkouts finds the workouts the user owns or that are shared with
+// them whose title, description, exercise names or entry notes match the
+// query, best matches first.
+func (pg *PostgresWorkoutStore) SearchWorkouts(filter SearchFilter) ([]WorkoutSearchResult, Metadata, error) {
+	query := `
+		SELECT count(*) OVER(), w.id, w.user_id, w.title, w.description, w.duration_minutes, w.calories_burned,
+			w.version, w.performed_at, w.created_at, w.updated_at,
+			ts_rank(w.search_vector, q) + COALESCE((
+				SELECT max(ts_rank(e.search_vector, q))
+				FROM workout_entries e
+				WHERE e.workout_id = w.id AND e.search_vector @@ q
+			), 0) AS rank,
+			CASE WHEN to_tsvector('english', w.title) @@ q THEN ts_headline('english', w.title, q, $5) END,
+			CASE WHEN to_tsvector('english', w.description) @@ q THEN ts_headline('english', w.description, q, $5) END
+		FROM workouts w, websearch_to_tsquery('english', $2) q
+		WHERE w.deleted_at IS NULL
+		AND (w.user_id = $1 OR EXISTS (
+			SELECT 1 FROM workout_shares s WHERE s.workout_id = w.id AND s.user_id = $1
+		))
+		AND (w.search_vector @@ q OR EXISTS (
+			SELECT 1 FROM workout_entries e WHERE e.workout_id = w.id AND e.search_vector @@ q
+		))
+		ORDER BY rank DESC, w.performed_at DESC, w.id DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := pg.db.Query(query, filter.UserID, filter.Query, filter.Limit, (filter.Page-1)*filter.Limit, headlineOptions)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	results := []WorkoutSearchResult{}
+	ids := []int64{}
+	for rows.Next() {
+		result := WorkoutSearchResult{Workout: &Workout{}, Highlights: []SearchHighlight{}}
+		workout := result.Workout
+		var title, description *string
+		err = rows.Scan(
+			&totalRecords,
+			&workout.ID,
+			&workout.UserID,
+			&workout.Title,
+			&workout.Description,
+			&workout.DurationMinutes,
+			&workout.CaloriesBurned,
+			&workout.Version,
+			&workout.PerformedAt,
+			&workout.CreatedAt,
+			&workout.UpdatedAt,
+			&result.Rank,
+			&title,
+			&description,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		result.Rank = roundTo(result.Rank, 4)
+		if title != nil {
+			result.Highlights = append(result.Highlights, SearchHighlight{Field: "title", Snippet: highlightSnippet(*title)})
+		}
+		if description != nil {
+			result.Highlights = append(result.Highlights, SearchHighlight{Field: "description", Snippet: highlightSnippet(*description)})
+		}
+		results = append(results, result)
+		ids = append(ids, int64(workout.ID))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	highlights, err := pg.entryHighlights(ids, filter.Query)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	workouts := make([]*Workout, len(results))
+	for i := range results {
+		workouts[i] = results[i].Workout
+		results[i].Highlights = append(results[i].Highlights, highlights[workouts[i].ID]...)
+	}
+	err = loadWorkoutDetails(pg.db, workouts)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return results, calculateMetadata(totalRecords, filter.Page, filter.Limit), nil
+}
+
+// entryHighlights returns the highlighted exercise names and notes of the
+// entries of the workouts that match the query, keyed by workout id.
+func (pg *PostgresWorkoutStore) entryHighlights(workoutIDs []int64, search string) (map[int][]SearchHighlight, error) {
+	highlights := make(map[int][]SearchHighlight, len(workoutIDs))
+	if len(workoutIDs) == 0 {
+		return highlights, nil
+	}
+
+	query := `
+		SELECT e.workout_id, e.id,
+			CASE WHEN to_tsvector('english', e.exercise_name) @@ q THEN ts_headline('english', e.exercise_name, q, $3) END,
+			CASE WHEN to_tsvector('english', COALESCE(e.notes, '')) @@ q THEN ts_headline('english', e.notes, q, $3) END
+		FROM workout_entries e, websearch_to_tsquery('english', $2) q
+		WHERE e.workout_id = ANY($1) AND e.search_vector @@ q
+		ORDER BY e.workout_id, e.order_index
+	`
+	rows, err := pg.db.Query(query, workoutIDs, search, headlineOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workoutID, entryID int
+		var exerciseName, notes *string
+		err = rows.Scan(&workoutID, &entryID, &exerciseName, &notes)
+		if err != nil {
+			return nil, err
+		}
+		if exerciseName != nil {
+			highlights[workoutID] = append(highlights[workoutID], SearchHighlight{Field: "exercise_name", EntryID: &entryID, Snippet: highlightSnippet(*exerciseName)})
+		}
+		if notes != nil {
+			highlights[workoutID] = append(highlights[workoutID], SearchHighlight{Field: "notes", EntryID: &entryID, Snippet: highlightSnippet(*notes)})
+		}
+	}
+
+	return highlights, rows.Err()
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSearchWorkouts(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	store := NewPostgresWorkoutStore(db)
+	user := createTestUser(t, db, "search_user")
+	other := createTestUser(t, db, "search_other")
+
+	_, err := store.CreateWorkout(&Workout{
+		UserID: user.ID,
+		Title:  "Push day",
+		Entries: []WorkoutEntry{
+			{ExerciseName: "Overhead Press", Sets: 3, Reps: createIntPtr(8), Notes: "my shoulder hurt on the last set", OrderIndex: 1},
+		},
+	})
+	require.NoError(t, err)
+	_, err = store.CreateWorkout(&Workout{UserID: user.ID, Title: "Shoulder mobility", Description: "easy recovery"})
+	require.NoError(t, err)
+	_, err = store.CreateWorkout(&Workout{
+		UserID: user.ID,
+		Title:  "Leg day",
+		Entries: []WorkoutEntry{
+			{ExerciseName: "Squat", Sets: 3, Reps: createIntPtr(5), Notes: `<script>alert("squat")</script> felt strong`, OrderIndex: 1},
+		},
+	})
+	require.NoError(t, err)
+	hidden, err := store.CreateWorkout(&Workout{UserID: other.ID, Title: "Shoulder day"})
+	require.NoError(t, err)
+
+	results, metadata, err := store.SearchWorkouts(SearchFilter{UserID: user.ID, Query: "shoulder hurt", Page: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, 1, metadata.TotalRecords)
+	assert.Equal(t, "Push day", results[0].Workout.Title)
+	require.Len(t, results[0].Highlights, 1)
+	assert.Equal(t, "notes", results[0].Highlights[0].Field)
+	assert.Contains(t, results[0].Highlights[0].Snippet, "<b>shoulder</b>")
+
+	results, _, err = store.SearchWorkouts(SearchFilter{UserID: user.ID, Query: "shoulder", Page: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	// a title match outranks a match in the notes
+	assert.Equal(t, "Shoulder mobility", results[0].Workout.Title)
+
+	// notes are escaped around the highlighted words
+	results, _, err = store.SearchWorkouts(SearchFilter{UserID: user.ID, Query: "strong", Page: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Len(t, results[0].Highlights, 1)
+	snippet := results[0].Highlights[0].Snippet
+	assert.NotContains(t, snippet, "<script>")
+	assert.Contains(t, snippet, "&lt;script&gt;")
+	assert.Contains(t, snippet, "<b>strong</b>")
+
+	_, err = store.ShareWorkout(int64(hidden.ID), user.Username)
+	require.NoError(t, err)
+	results, _, err = store.SearchWorkouts(SearchFilter{UserID: user.ID, Query: "shoulder", Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, results, 3)
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	headline := "<img src=x onerror=alert(1)> lifted \x02heavy\x03 & \x02fast\x03"
+	assert.Equal(t, "&lt;img src=x onerror=alert(1)&gt; lifted <b>heavy</b> &amp; <b>fast</b>", highlightSnippet(headline))
+}
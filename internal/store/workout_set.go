@@ -0,0 +1,92 @@
+package store
+
+import "math"
+
+// WorkoutSet is a single logged set of a workout entry.
+type WorkoutSet struct {
+	ID              int      `json:"id"`
+	SetNumber       int      `json:"set_number"`
+	Reps            *int     `json:"reps"`
+	Weight          *float64 `json:"weight"`
+	DurationSeconds *int     `json:"duration_seconds"`
+	RPE             *float64 `json:"rpe"`
+	RIR             *int     `json:"rir"`
+	IsWarmup        bool     `json:"is_warmup"`
+}
+
+// NormalizeSets keeps the per-set log and the flat sets/reps/weight fields of
+// the entry in agreement. When sets are logged individually the flat fields
+// are derived from them, otherwise the flat fields are expanded into
+// identical sets.
+func (e *WorkoutEntry) NormalizeSets() {
+	if e.EntryType == EntryTypeCardio && e.Sets < 1 && len(e.SetDetails) == 0 {
+		// a continuous cardio effort is a single set
+		e.Sets = 1
+	}
+
+	if len(e.SetDetails) == 0 {
+		e.SetDetails = make([]WorkoutSet, 0, e.Sets)
+		for i := 0; i < e.Sets; i++ {
+			e.SetDetails = append(e.SetDetails, WorkoutSet{
+				SetNumber:       i + 1,
+				Reps:            e.Reps,
+				Weight:          e.Weight,
+				DurationSeconds: e.DurationSeconds,
+			})
+		}
+		return
+	}
+
+	for i := range e.SetDetails {
+		e.SetDetails[i].SetNumber = i + 1
+	}
+
+	working := make([]WorkoutSet, 0, len(e.SetDetails))
+	for _, set := range e.SetDetails {
+		if !set.IsWarmup {
+			working = append(working, set)
+		}
+	}
+	if len(working) == 0 {
+		working = e.SetDetails
+	}
+
+	e.Sets = len(working)
+	top := topSet(working)
+	if top.Reps != nil {
+		e.Reps, e.DurationSeconds, e.Weight = top.Reps, nil, top.Weight
+		return
+	}
+
+	// timed sets report their longest hold
+	e.Reps, e.DurationSeconds, e.Weight = nil, top.DurationSeconds, top.Weight
+	for _, set := range working {
+		if set.DurationSeconds != nil && (e.DurationSeconds == nil || *set.DurationSeconds > *e.DurationSeconds) {
+			e.DurationSeconds = set.DurationSeconds
+		}
+	}
+}
+
+// topSet picks the heaviest set, preferring more reps between equal weights.
+func topSet(sets []WorkoutSet) WorkoutSet {
+	top := sets[0]
+	for _, set := range sets[1:] {
+		weight, topWeight := valueOrZero(set.Weight), valueOrZero(top.Weight)
+		if weight > topWeight || (weight == topWeight && valueOrZero(set.Reps) > valueOrZero(top.Reps)) {
+			top = set
+		}
+	}
+	return top
+}
+
+func valueOrZero[T int | float64](v *T) T {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func roundTo(v float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(v*factor) / factor
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeSets(t *testing.T) {
+	tests := []struct {
+		name         string
+		entry        WorkoutEntry
+		wantSets     int
+		wantReps     *int
+		wantWeight   *float64
+		wantDuration *int
+		wantDetails  int
+	}{
+		{
+			name:        "flat shape is expanded",
+			entry:       WorkoutEntry{Sets: 3, Reps: createIntPtr(10), Weight: createFloatPtr(60)},
+			wantSets:    3,
+			wantReps:    createIntPtr(10),
+			wantWeight:  createFloatPtr(60),
+			wantDetails: 3,
+		},
+		{
+			name: "pyramid with warm-up derives the top working set",
+			entry: WorkoutEntry{
+				SetDetails: []WorkoutSet{
+					{Reps: createIntPtr(12), Weight: createFloatPtr(40), IsWarmup: true},
+					{Reps: createIntPtr(10), Weight: createFloatPtr(60)},
+					{Reps: createIntPtr(8), Weight: createFloatPtr(70)},
+					{Reps: createIntPtr(3), Weight: createFloatPtr(80)},
+				},
+			},
+			wantSets:    3,
+			wantReps:    createIntPtr(3),
+			wantWeight:  createFloatPtr(80),
+			wantDetails: 4,
+		},
+		{
+			name: "timed sets report the longest hold",
+			entry: WorkoutEntry{
+				SetDetails: []WorkoutSet{
+					{DurationSeconds: createIntPtr(60)},
+					{DurationSeconds: createIntPtr(90)},
+					{DurationSeconds: createIntPtr(45)},
+				},
+			},
+			wantSets:     3,
+			wantDuration: createIntPtr(90),
+			wantDetails:  3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.entry.NormalizeSets()
+			assert.Equal(t, tt.wantSets, tt.entry.Sets)
+			assert.Equal(t, tt.wantReps, tt.entry.Reps)
+			assert.Equal(t, tt.wantWeight, tt.entry.Weight)
+			assert.Equal(t, tt.wantDuration, tt.entry.DurationSeconds)
+			assert.Len(t, tt.entry.SetDetails, tt.wantDetails)
+			for i, set := range tt.entry.SetDetails {
+				assert.Equal(t, i+1, set.SetNumber)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// WorkoutShare lets another user view and duplicate a workout.
+type WorkoutShare struct {
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ShareWorkout shares the workout with the user called username. It returns
+// sql.ErrNoRows when there is no such user.
+func (pg *PostgresWorkoutStore) ShareWorkout(workoutID int64, username string) (*WorkoutShare, error) {
+	query := `
+		WITH shared AS (
+			INSERT INTO workout_shares (workout_id, user_id)
+			SELECT $1, id FROM users WHERE username = $2
+			ON CONFLICT (workout_id, user_id) DO UPDATE SET workout_id = EXCLUDED.workout_id
+			RETURNING user_id, created_at
+		)
+		SELECT shared.user_id, users.username, shared.created_at
+		FROM shared
+		JOIN users ON users.id = shared.user_id
+	`
+	share := &WorkoutShare{}
+	err := pg.db.QueryRow(query, workoutID, username).Scan(&share.UserID, &share.Username, &share.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return share, nil
+}
+
+// UnshareWorkout stops sharing the workout with the user. It returns
+// sql.ErrNoRows when the workout was not shared with them.
+func (pg *PostgresWorkoutStore) UnshareWorkout(workoutID int64, userID int) error {
+	result, err := pg.db.Exec(`DELETE FROM workout_shares WHERE workout_id = $1 AND user_id = $2`, workoutID, userID)
+	if err != nil {
+		return err
+	}
+	resultRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if resultRows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (pg *PostgresWorkoutStore) ListWorkoutShares(workoutID int64) ([]WorkoutShare, error) {
+	query := `
+		SELECT s.user_id, u.username, s.created_at
+		FROM workout_shares s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.workout_id = $1
+		ORDER BY u.username
+	`
+	rows, err := pg.db.Query(query, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []WorkoutShare{}
+	for rows.Next() {
+		var share WorkoutShare
+		err = rows.Scan(&share.UserID, &share.Username, &share.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+// CanViewWorkout reports whether the user owns the workout or it is shared
+// with them. Workouts in the trash are only visible to their owner.
+func (pg *PostgresWorkoutStore) CanViewWorkout(workoutID int64, userID int) (bool, error) {
+	query := `
+		SELECT w.user_id = $2 OR (w.deleted_at IS NULL AND EXISTS (
+			SELECT 1 FROM workout_shares s WHERE s.workout_id = w.id AND s.user_id = $2
+		))
+		FROM workouts w
+		WHERE w.id = $1
+	`
+	var visible bool
+	err := pg.db.QueryRow(query, workoutID, userID).Scan(&visible)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	return visible, err
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestShareAndDuplicateWorkout(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	store := NewPostgresWorkoutStore(db)
+	owner := createTestUser(t, db, "share_owner")
+	friend := createTestUser(t, db, "share_friend")
+
+	workout, err := store.CreateWorkout(&Workout{
+		UserID: owner.ID,
+		Title:  "arm day",
+		Tags:   []string{"hypertrophy"},
+		Entries: []WorkoutEntry{
+			{ExerciseName: "Curls", Sets: 3, Reps: createIntPtr(12), Weight: createFloatPtr(15), OrderIndex: 1},
+			{ExerciseName: "Dips", Sets: 3, Reps: createIntPtr(10), OrderIndex: 2},
+		},
+	})
+	require.NoError(t, err)
+
+	visible, err := store.CanViewWorkout(int64(workout.ID), friend.ID)
+	require.NoError(t, err)
+	assert.False(t, visible)
+
+	_, err = store.ShareWorkout(int64(workout.ID), "nobody")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	share, err := store.ShareWorkout(int64(workout.ID), friend.Username)
+	require.NoError(t, err)
+	assert.Equal(t, friend.ID, share.UserID)
+	_, err = store.ShareWorkout(int64(workout.ID), friend.Username)
+	require.NoError(t, err)
+
+	shares, err := store.ListWorkoutShares(int64(workout.ID))
+	require.NoError(t, err)
+	assert.Len(t, shares, 1)
+
+	visible, err = store.CanViewWorkout(int64(workout.ID), friend.ID)
+	require.NoError(t, err)
+	assert.True(t, visible)
+
+	performedAt := time.Date(2024, 3, 1, 7, 0, 0, 0, time.UTC)
+	copied, err := store.DuplicateWorkout(int64(workout.ID), friend.ID, "", &performedAt)
+	require.NoError(t, err)
+	assert.NotEqual(t, workout.ID, copied.ID)
+	assert.Equal(t, friend.ID, copied.UserID)
+	assert.Equal(t, "arm day", copied.Title)
+	assert.True(t, performedAt.Equal(copied.PerformedAt))
+	require.Len(t, copied.Entries, 2)
+	assert.NotEqual(t, workout.Entries[0].ID, copied.Entries[0].ID)
+	assert.Equal(t, 15.0, *copied.Entries[0].Weight)
+	// the tags of the owner stay with the owner
+	assert.Empty(t, copied.Tags)
+	tags, err := store.ListTags(friend.ID)
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+
+	own, err := store.DuplicateWorkout(int64(workout.ID), owner.ID, "arm day again", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hypertrophy"}, own.Tags)
+
+	require.NoError(t, store.UnshareWorkout(int64(workout.ID), friend.ID))
+	assert.ErrorIs(t, store.UnshareWorkout(int64(workout.ID), friend.ID), sql.ErrNoRows)
+}
@@ -3,6 +3,10 @@ package store
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"github.com/helmigandi/go-workout-api/internal/fitness"
+	"slices"
+	"time"
 )
 
 type Workout struct {
@@ -13,17 +17,62 @@ type Workout struct {
 	DurationMinutes int            `json:"duration_minutes"`
 	CaloriesBurned  int            `json:"calories_burned"`
 	Entries         []WorkoutEntry `json:"entries"`
+	Groups          []WorkoutGroup `json:"groups"`
+	Tags            []string       `json:"tags"`
+	Version         int            `json:"version"`
+	PerformedAt     time.Time      `json:"performed_at"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       *time.Time     `json:"deleted_at,omitempty"`
 }
 
+var ErrUnknownExercise = errors.New("unknown exercise")
+
+// ErrEditConflict means the workout was changed since the version the caller
+// based its change on.
+var ErrEditConflict = errors.New("edit conflict")
+
 type WorkoutEntry struct {
-	ID              int      `json:"id"`
-	ExerciseName    string   `json:"exercise_name"`
+	ID int `json:"id"`
+	// ExerciseID links the entry to the exercise catalog. ExerciseName is
+	// kept for clients that only send free text.
+	ExerciseID   *int   `json:"exercise_id"`
+	ExerciseName string `json:"exercise_name"`
+	// EntryType is strength or cardio, see Validate for the fields each
+	// type requires.
+	EntryType       string   `json:"entry_type"`
 	Sets            int      `json:"sets"`
 	Reps            *int     `json:"reps"`
 	DurationSeconds *int     `json:"duration_seconds"`
 	Weight          *float64 `json:"weight"`
-	Notes           string   `json:"notes"`
-	OrderIndex      int      `json:"order_index"`
+	// Distance is measured in DistanceUnit. Pace (seconds per unit) and
+	// Speed (units per hour) are derived from it and the duration.
+	Distance            *float64 `json:"distance"`
+	DistanceUnit        string   `json:"distance_unit"`
+	Pace                *float64 `json:"pace"`
+	Speed               *float64 `json:"speed"`
+	ElevationGainMeters *int     `json:"elevation_gain_meters"`
+	AvgHeartRate        *int     `json:"avg_heart_rate"`
+	MaxHeartRate        *int     `json:"max_heart_rate"`
+	// WeightUnit is the unit of the weights of this value, LoggedWeightUnit
+	// the one the entry was originally logged in.
+	WeightUnit       string `json:"weight_unit"`
+	LoggedWeightUnit string `json:"logged_weight_unit"`
+	Notes            string `json:"notes"`
+	OrderIndex       int    `json:"order_index"`
+	// GroupLabel places the entry in one of the supersets or circuits of
+	// the workout.
+	GroupLabel string `json:"group_label"`
+	// SetDetails logs every set individually. Sets, Reps, Weight and
+	// DurationSeconds are derived from it, see NormalizeSets.
+	SetDetails []WorkoutSet `json:"set_details"`
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx, so loading can happen
+// inside or outside of a transaction.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
 }
 
 type PostgresWorkoutStore struct {
@@ -38,10 +87,33 @@ func NewPostgresWorkoutStore(db *sql.DB) *PostgresWorkoutStore {
 
 type WorkoutStore interface {
 	CreateWorkout(*Workout) (*Workout, error)
+	CreateWorkoutWithTrack(workout *Workout, points []TrackPoint) (*Workout, error)
+	ListTrackPoints(workoutID int64, maxPoints int) ([]TrackPoint, int, error)
 	GetWorkoutByID(id int64) (*Workout, error)
-	UpdateWorkout(*Workout) error
-	DeleteWorkout(id int64) error
+	ListWorkouts(filter WorkoutFilter) ([]*Workout, Metadata, error)
+	SearchWorkouts(filter SearchFilter) ([]WorkoutSearchResult, Metadata, error)
+	ExportWorkouts(filter ExportFilter, fn func(*Workout) error) error
+	AddWorkoutTags(workout *Workout, names []string, actorID int) error
+	RemoveWorkoutTag(workout *Workout, name string, actorID int) error
+	ListTags(userID int) ([]TagCount, error)
+	UpdateWorkout(workout *Workout, actorID int) error
+	DeleteWorkout(id int64, version int, actorID int) error
+	ListDeletedWorkouts(userID int) ([]*Workout, error)
+	RestoreWorkout(id int64, userID int) error
+	PurgeDeletedWorkouts(deletedBefore time.Time) (int64, error)
+	ListRevisions(workoutID int64) ([]WorkoutRevision, error)
+	RollbackWorkout(workoutID, revisionID int64, version int, actorID int) (*Workout, error)
+	DuplicateWorkout(workoutID int64, userID int, title string, performedAt *time.Time) (*Workout, error)
+	ShareWorkout(workoutID int64, username string) (*WorkoutShare, error)
+	UnshareWorkout(workoutID int64, userID int) error
+	ListWorkoutShares(workoutID int64) ([]WorkoutShare, error)
+	CanViewWorkout(workoutID int64, userID int) (bool, error)
 	GetWorkoutOwner(id int64) (int, error)
+	WorkoutExists(userID int, title string, performedAt time.Time) (bool, error)
+	CreateEntry(workout *Workout, entry *WorkoutEntry, actorID int) error
+	UpdateEntry(workout *Workout, entry *WorkoutEntry, actorID int) error
+	DeleteEntry(workout *Workout, entryID int64, actorID int) error
+	ReorderEntries(workout *Workout, entryIDs []int64, actorID int) error
 }
 
 func (pg *PostgresWorkoutStore) CreateWorkout(workout *Workout) (*Workout, error) {
@@ -51,41 +123,11 @@ func (pg *PostgresWorkoutStore) CreateWorkout(workout *Workout) (*Workout, error
 	}
 	defer tx.Rollback()
 
-	query :=
-		`
-  INSERT INTO workouts (user_id, title, description, duration_minutes, calories_burned)
-  VALUES ($1, $2, $3, $4, $5)
-  RETURNING id 
-  `
-
-	err = tx.QueryRow(query, workout.UserID, workout.Title, workout.Description, workout.DurationMinutes, workout.CaloriesBurned).Scan(&workout.ID)
+	err = createWorkout(tx, workout)
 	if err != nil {
 		return nil, err
 	}
 
-	// we also need to insert the entries
-	for i := range workout.Entries {
-		entry := &workout.Entries[i]
-		query := `
-			INSERT INTO workout_entries (workout_id, exercise_name, sets, reps, duration_seconds, weight, notes, order_index)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-			RETURNING id
-		`
-		err = tx.QueryRow(query,
-			workout.ID,
-			entry.ExerciseName,
-			entry.Sets,
-			entry.Reps,
-			entry.DurationSeconds,
-			entry.Weight,
-			entry.Notes,
-			entry.OrderIndex).
-			Scan(&entry.ID)
-		if err != nil {
-			return nil, err
-		}
-	}
-
 	err = tx.Commit()
 	if err != nil {
 		return nil, err
@@ -95,13 +137,35 @@ func (pg *PostgresWorkoutStore) CreateWorkout(workout *Workout) (*Workout, error
 }
 
 func (pg *PostgresWorkoutStore) GetWorkoutByID(id int64) (*Workout, error) {
+	workout, err := getWorkout(pg.db, id)
+	if err != nil || workout == nil || workout.DeletedAt != nil {
+		return nil, err
+	}
+	return workout, nil
+}
+
+// getWorkout loads the workout with its entries and groups, including a
+// workout that is in the trash. It returns nil when there is no such workout.
+func getWorkout(q queryer, id int64) (*Workout, error) {
 	workout := &Workout{}
 	query := `
-		SELECT id, title, description, duration_minutes, calories_burned 
+		SELECT id, user_id, title, description, duration_minutes, calories_burned, version, performed_at, created_at, updated_at, deleted_at
 		FROM workouts 
 		WHERE id = $1
 	`
-	err := pg.db.QueryRow(query, id).Scan(&workout.ID, &workout.Title, &workout.Description, &workout.DurationMinutes, &workout.CaloriesBurned)
+	err := q.QueryRow(query, id).Scan(
+		&workout.ID,
+		&workout.UserID,
+		&workout.Title,
+		&workout.Description,
+		&workout.DurationMinutes,
+		&workout.CaloriesBurned,
+		&workout.Version,
+		&workout.PerformedAt,
+		&workout.CreatedAt,
+		&workout.UpdatedAt,
+		&workout.DeletedAt,
+	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -110,117 +174,795 @@ func (pg *PostgresWorkoutStore) GetWorkoutByID(id int64) (*Workout, error) {
 		return nil, err
 	}
 
-	// get the entries
-	entryQuery := `
-		SELECT id, exercise_name, sets, reps, duration_seconds, weight, notes, order_index
+	err = loadWorkoutDetails(q, []*Workout{workout})
+	if err != nil {
+		return nil, err
+	}
+
+	return workout, nil
+}
+
+func (pg *PostgresWorkoutStore) ListWorkouts(filter WorkoutFilter) ([]*Workout, Metadata, error) {
+	var cursor *workoutCursor
+	if filter.Cursor != "" {
+		if !filter.CursorSortable() {
+			return nil, Metadata{}, ErrInvalidCursor
+		}
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		if !c.matches(filter) {
+			return nil, Metadata{}, fmt.Errorf("%w: it belongs to another sort order or filter", ErrInvalidCursor)
+		}
+		cursor = &c
+	}
+
+	args := []any{
+		filter.UserID,
+		filter.From,
+		filter.To,
+		escapeLike(filter.Title),
+		filter.MinDuration,
+		filter.Tags,
+		filter.MatchAllTags,
+	}
+	// with match all tags a workout has to carry every tag, otherwise one
+	where := `
+		WHERE user_id = $1 AND deleted_at IS NULL
+		AND ($2::timestamptz IS NULL OR performed_at >= $2)
+		AND ($3::timestamptz IS NULL OR performed_at < $3)
+		AND ($4 = '' OR title ILIKE '%' || $4 || '%')
+		AND ($5::integer IS NULL OR duration_minutes >= $5)
+		AND (COALESCE(cardinality($6::text[]), 0) = 0 OR (
+			SELECT count(*) FROM workout_tags wt
+			JOIN tags t ON t.id = wt.tag_id
+			WHERE wt.workout_id = workouts.id AND t.name = ANY($6)
+		) >= CASE WHEN $7::boolean THEN cardinality($6::text[]) ELSE 1 END)
+	`
+
+	direction := filter.sortDirection()
+	var query string
+	if cursor == nil {
+		query = fmt.Sprintf(`
+			SELECT count(*) OVER(), id, user_id, title, description, duration_minutes, calories_burned,
+				version, performed_at, created_at, updated_at
+			FROM workouts
+			%s
+			ORDER BY %s %s, id %s
+			LIMIT $8 OFFSET $9
+		`, where, filter.sortColumn(), direction, direction)
+		args = append(args, filter.limit(), filter.offset())
+	} else {
+		// paging backwards walks the listing in reverse and flips the page
+		// back into order once it has been read
+		comparison := ">"
+		if direction == "DESC" {
+			comparison = "<"
+		}
+		if cursor.Backward {
+			comparison = map[string]string{">": "<", "<": ">"}[comparison]
+			direction = map[string]string{"ASC": "DESC", "DESC": "ASC"}[direction]
+		}
+
+		// one extra row tells whether there is another page after this one
+		query = fmt.Sprintf(`
+			SELECT 0, id, user_id, title, description, duration_minutes, calories_burned,
+				version, performed_at, created_at, updated_at
+			FROM workouts
+			%s
+			AND (created_at, id) %s ($8, $9)
+			ORDER BY created_at %s, id %s
+			LIMIT $10
+		`, where, comparison, direction, direction)
+		args = append(args, cursor.CreatedAt, cursor.ID, filter.limit()+1)
+	}
+
+	rows, err := pg.db.Query(query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	workouts := []*Workout{}
+	for rows.Next() {
+		workout := &Workout{}
+		err = rows.Scan(
+			&totalRecords,
+			&workout.ID,
+			&workout.UserID,
+			&workout.Title,
+			&workout.Description,
+			&workout.DurationMinutes,
+			&workout.CaloriesBurned,
+			&workout.Version,
+			&workout.PerformedAt,
+			&workout.CreatedAt,
+			&workout.UpdatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		workouts = append(workouts, workout)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	var metadata Metadata
+	hasPrev, hasNext := false, false
+	if cursor == nil {
+		metadata = calculateMetadata(totalRecords, filter.Page, filter.Limit)
+		hasPrev = filter.Page > 1 && len(workouts) > 0
+		hasNext = filter.offset()+len(workouts) < totalRecords
+	} else {
+		metadata = Metadata{PageSize: filter.Limit}
+		hasMore := len(workouts) > filter.Limit
+		if hasMore {
+			workouts = workouts[:filter.Limit]
+		}
+		if cursor.Backward {
+			slices.Reverse(workouts)
+			hasPrev, hasNext = hasMore, true
+		} else {
+			hasPrev, hasNext = true, hasMore
+		}
+	}
+
+	if filter.CursorSortable() && len(workouts) > 0 {
+		last := len(workouts) - 1
+		next := workoutCursor{CreatedAt: workouts[last].CreatedAt, ID: workouts[last].ID, Direction: filter.sortDirection(), Filter: cursorFilterHash(filter)}
+		prev := workoutCursor{CreatedAt: workouts[0].CreatedAt, ID: workouts[0].ID, Backward: true, Direction: next.Direction, Filter: next.Filter}
+		if hasNext {
+			metadata.NextCursor = encodeCursor(next)
+		}
+		if hasPrev {
+			metadata.PrevCursor = encodeCursor(prev)
+		}
+	}
+
+	// load the entries of the whole page in a single query
+	err = loadWorkoutDetails(pg.db, workouts)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return workouts, metadata, nil
+}
+
+// loadWorkoutDetails fills in the entries, groups and tags of the workouts
+// with one query each.
+func loadWorkoutDetails(q queryer, workouts []*Workout) error {
+	ids := make([]int64, len(workouts))
+	for i, workout := range workouts {
+		ids[i] = int64(workout.ID)
+	}
+
+	entries, err := loadEntries(q, ids)
+	if err != nil {
+		return err
+	}
+	groups, err := loadGroups(q, ids)
+	if err != nil {
+		return err
+	}
+	tags, err := loadTags(q, ids)
+	if err != nil {
+		return err
+	}
+	for _, workout := range workouts {
+		workout.Entries = entries[workout.ID]
+		workout.Groups = groups[workout.ID]
+		workout.Tags = tags[workout.ID]
+		if workout.Tags == nil {
+			workout.Tags = []string{}
+		}
+	}
+
+	return nil
+}
+
+// loadEntries fetches the entries of every given workout, keyed by workout id.
+func loadEntries(q queryer, workoutIDs []int64) (map[int][]WorkoutEntry, error) {
+	entries := make(map[int][]WorkoutEntry, len(workoutIDs))
+	if len(workoutIDs) == 0 {
+		return entries, nil
+	}
+
+	query := `
+		SELECT workout_id, id, exercise_id, exercise_name, entry_type, sets, reps, duration_seconds, weight, weight_unit,
+			distance, COALESCE(distance_unit, ''), elevation_gain_meters, avg_heart_rate, max_heart_rate, notes, order_index,
+			COALESCE(group_label, '')
 		FROM workout_entries
-		WHERE workout_id = $1
-		ORDER BY order_index
+		WHERE workout_id = ANY($1)
+		ORDER BY workout_id, order_index, id
 	`
-	rows, err := pg.db.Query(entryQuery, id)
+	rows, err := q.Query(query, workoutIDs)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	entryIDs := []int64{}
 	for rows.Next() {
-		var entry WorkoutEntry
+		var workoutID int
+		entry := WorkoutEntry{WeightUnit: fitness.UnitKilogram}
 		err = rows.Scan(
+			&workoutID,
 			&entry.ID,
+			&entry.ExerciseID,
 			&entry.ExerciseName,
+			&entry.EntryType,
 			&entry.Sets,
 			&entry.Reps,
 			&entry.DurationSeconds,
 			&entry.Weight,
+			&entry.LoggedWeightUnit,
+			&entry.Distance,
+			&entry.DistanceUnit,
+			&entry.ElevationGainMeters,
+			&entry.AvgHeartRate,
+			&entry.MaxHeartRate,
 			&entry.Notes,
 			&entry.OrderIndex,
+			&entry.GroupLabel,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entry.derivePace()
+		entries[workoutID] = append(entries[workoutID], entry)
+		entryIDs = append(entryIDs, int64(entry.ID))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sets, err := loadSets(q, entryIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, workoutEntries := range entries {
+		for i := range workoutEntries {
+			workoutEntries[i].SetDetails = sets[workoutEntries[i].ID]
+		}
+	}
+
+	return entries, nil
+}
+
+// loadSets fetches the sets of every given entry, keyed by entry id.
+func loadSets(q queryer, entryIDs []int64) (map[int][]WorkoutSet, error) {
+	sets := make(map[int][]WorkoutSet, len(entryIDs))
+	if len(entryIDs) == 0 {
+		return sets, nil
+	}
+
+	query := `
+		SELECT workout_entry_id, id, set_number, reps, weight, duration_seconds, rpe, rir, is_warmup
+		FROM workout_sets
+		WHERE workout_entry_id = ANY($1)
+		ORDER BY workout_entry_id, set_number
+	`
+	rows, err := q.Query(query, entryIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryID int
+		var set WorkoutSet
+		err = rows.Scan(
+			&entryID,
+			&set.ID,
+			&set.SetNumber,
+			&set.Reps,
+			&set.Weight,
+			&set.DurationSeconds,
+			&set.RPE,
+			&set.RIR,
+			&set.IsWarmup,
 		)
 		if err != nil {
 			return nil, err
 		}
-		workout.Entries = append(workout.Entries, entry)
+		sets[entryID] = append(sets[entryID], set)
+	}
+
+	return sets, rows.Err()
+}
+
+// DuplicateWorkout copies the workout with its entries and groups into a new
+// workout of userID. The copy keeps the title and is performed now unless
+// title or performedAt are given. Exercises that userID cannot see are
+// copied by name only, and the tags are only copied onto a workout of the
+// same owner, as they belong to the owner.
+func (pg *PostgresWorkoutStore) DuplicateWorkout(workoutID int64, userID int, title string, performedAt *time.Time) (*Workout, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	workout, err := getWorkout(tx, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	if workout == nil || workout.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+
+	if workout.UserID != userID {
+		workout.Tags = []string{}
+	}
+	workout.ID = 0
+	workout.UserID = userID
+	workout.PerformedAt = time.Time{}
+	if performedAt != nil {
+		workout.PerformedAt = *performedAt
+	}
+	if title != "" {
+		workout.Title = title
+	}
+
+	exerciseIDs := []int64{}
+	for _, entry := range workout.Entries {
+		if entry.ExerciseID != nil {
+			exerciseIDs = append(exerciseIDs, int64(*entry.ExerciseID))
+		}
+	}
+	visible, err := exerciseNames(tx, userID, exerciseIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range workout.Entries {
+		entry := &workout.Entries[i]
+		entry.ID = 0
+		if entry.ExerciseID != nil {
+			if _, ok := visible[*entry.ExerciseID]; !ok {
+				entry.ExerciseID = nil
+			}
+		}
+	}
+
+	err = createWorkout(tx, workout)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
 	}
 
 	return workout, nil
 }
 
-func (pg *PostgresWorkoutStore) UpdateWorkout(workout *Workout) error {
+func createWorkout(tx *sql.Tx, workout *Workout) error {
+	query :=
+		`
+  INSERT INTO workouts (user_id, title, description, duration_minutes, calories_burned, performed_at)
+  VALUES ($1, $2, $3, $4, $5, COALESCE($6, CURRENT_TIMESTAMP))
+  RETURNING id, version, performed_at, created_at, updated_at
+  `
+
+	err := tx.QueryRow(query, workout.UserID, workout.Title, workout.Description, workout.DurationMinutes, workout.CaloriesBurned, nullTime(workout.PerformedAt)).
+		Scan(&workout.ID, &workout.Version, &workout.PerformedAt, &workout.CreatedAt, &workout.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	// we also need to insert the entries
+	err = insertEntries(tx, workout)
+	if err != nil {
+		return err
+	}
+
+	// the tags are returned as they are stored, never null
+	workout.Tags, err = sortedTags(workout.Tags)
+	if err != nil {
+		return err
+	}
+	err = addTags(tx, workout.UserID, int64(workout.ID), workout.Tags)
+	if err != nil {
+		return err
+	}
+
+	// workouts are only ever created by their owner
+	return recordRevision(tx, int64(workout.ID), workout.UserID, RevisionCreate, nil)
+}
+
+// UpdateWorkout saves the workout if it is still at workout.Version and
+// returns ErrEditConflict otherwise. The new version is set on the workout.
+// The change is recorded as made by actorID.
+func (pg *PostgresWorkoutStore) UpdateWorkout(workout *Workout, actorID int) error {
 	tx, err := pg.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	before, err := snapshotForUpdate(tx, int64(workout.ID))
+	if err != nil {
+		return err
+	}
+	if before == nil || before.DeletedAt != nil {
+		return sql.ErrNoRows
+	}
+
+	err = updateWorkout(tx, workout, before, RevisionUpdate, actorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateWorkout writes the workout, which was locked by snapshotForUpdate
+// and found as before, and records the change by actorID as a revision of
+// action.
+func updateWorkout(tx *sql.Tx, workout *Workout, before *Workout, action string, actorID int) error {
+	if workout.Version != before.Version {
+		return ErrEditConflict
+	}
+
 	query := `
 		UPDATE workouts
-		SET title = $1, description = $2, duration_minutes = $3, calories_burned = $4
-		WHERE id = $5
+		SET title = $1, description = $2, duration_minutes = $3, calories_burned = $4,
+			performed_at = COALESCE($5, performed_at), updated_at = CURRENT_TIMESTAMP, version = version + 1
+		WHERE id = $6
+		RETURNING version, performed_at, updated_at
 	`
-	result, err := tx.Exec(query, workout.Title, workout.Description, workout.DurationMinutes, workout.CaloriesBurned, workout.ID)
+	err := tx.QueryRow(query,
+		workout.Title,
+		workout.Description,
+		workout.DurationMinutes,
+		workout.CaloriesBurned,
+		nullTime(workout.PerformedAt),
+		workout.ID,
+	).Scan(&workout.Version, &workout.PerformedAt, &workout.UpdatedAt)
 	if err != nil {
 		return err
 	}
-	resultRows, err := result.RowsAffected()
+
+	err = reconcileEntries(tx, workout)
 	if err != nil {
 		return err
 	}
-	if resultRows == 0 {
-		return sql.ErrNoRows
+
+	workout.Tags, err = sortedTags(workout.Tags)
+	if err != nil {
+		return err
+	}
+	err = setTags(tx, workout.UserID, int64(workout.ID), workout.Tags)
+	if err != nil {
+		return err
+	}
+
+	return recordRevision(tx, int64(workout.ID), actorID, action, before)
+}
+
+func insertEntries(tx *sql.Tx, workout *Workout) error {
+	err := workout.ValidateGroups()
+	if err != nil {
+		return err
 	}
+	slices.SortStableFunc(workout.Entries, func(a, b WorkoutEntry) int { return a.OrderIndex - b.OrderIndex })
 
-	// delete all entries for this workout
-	_, err = tx.Exec(`DELETE FROM workout_entries WHERE workout_id = $1`, workout.ID)
+	err = upsertGroups(tx, workout)
 	if err != nil {
 		return err
 	}
 
-	// reinsert the entries
-	for _, entry := range workout.Entries {
-		query := `
-			INSERT INTO workout_entries (workout_id, exercise_name, sets, reps, duration_seconds, weight, notes, order_index)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		`
-		_, err := tx.Exec(query,
-			workout.ID,
-			entry.ExerciseName,
-			entry.Sets,
-			entry.Reps,
-			entry.DurationSeconds,
-			entry.Weight,
-			entry.Notes,
-			entry.OrderIndex,
-		)
+	err = resolveExercises(tx, workout.UserID, workout.Entries)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]*WorkoutEntry, len(workout.Entries))
+	for i := range workout.Entries {
+		entries[i] = &workout.Entries[i]
+	}
+
+	return insertEntryBatch(tx, workout.ID, entries)
+}
+
+// reconcileEntries brings the stored entries of the workout in line with
+// workout.Entries. Entries with an id are updated in place, entries without
+// one are inserted and stored entries that are no longer listed are deleted.
+func reconcileEntries(tx *sql.Tx, workout *Workout) error {
+	err := workout.ValidateGroups()
+	if err != nil {
+		return err
+	}
+	slices.SortStableFunc(workout.Entries, func(a, b WorkoutEntry) int { return a.OrderIndex - b.OrderIndex })
+
+	rows, err := tx.Query(`SELECT id FROM workout_entries WHERE workout_id = $1`, workout.ID)
+	if err != nil {
+		return err
+	}
+	stored := map[int]bool{}
+	for rows.Next() {
+		var id int
+		err = rows.Scan(&id)
 		if err != nil {
+			rows.Close()
 			return err
 		}
+		stored[id] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	kept := []int64{}
+	updated := []*WorkoutEntry{}
+	inserted := []*WorkoutEntry{}
+	for i := range workout.Entries {
+		entry := &workout.Entries[i]
+		if entry.ID == 0 {
+			inserted = append(inserted, entry)
+			continue
+		}
+		if !stored[entry.ID] {
+			return invalidEntry("entry %d does not belong to the workout", entry.ID)
+		}
+		if slices.Contains(kept, int64(entry.ID)) {
+			return invalidEntry("entry %d is listed twice", entry.ID)
+		}
+		kept = append(kept, int64(entry.ID))
+		updated = append(updated, entry)
+	}
+
+	err = upsertGroups(tx, workout)
+	if err != nil {
+		return err
+	}
+
+	err = resolveExercises(tx, workout.UserID, workout.Entries)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM workout_entries WHERE workout_id = $1 AND NOT (id = ANY($2))`, workout.ID, kept)
+	if err != nil {
+		return err
+	}
+
+	err = updateEntryBatch(tx, workout.ID, updated)
+	if err != nil {
+		return err
+	}
+
+	err = insertEntryBatch(tx, workout.ID, inserted)
+	if err != nil {
+		return err
+	}
+
+	return deleteStaleGroups(tx, workout)
+}
+
+// prepareEntry validates the entry and brings it into its stored shape.
+func prepareEntry(entry *WorkoutEntry) error {
+	err := entry.Validate()
+	if err != nil {
+		return err
+	}
+	if entry.EntryType == "" {
+		entry.EntryType = EntryTypeStrength
+	}
+	err = entry.canonicalizeWeights()
+	if err != nil {
+		return err
+	}
+	entry.NormalizeSets()
+	return nil
+}
+
+// resolveExercises makes sure every exercise referenced by the entries is
+// visible to userID and fills in the exercise name from the catalog where the
+// client left it empty.
+func resolveExercises(tx *sql.Tx, userID int, entries []WorkoutEntry) error {
+	ids := []int64{}
+	for _, entry := range entries {
+		if entry.ExerciseID != nil {
+			ids = append(ids, int64(*entry.ExerciseID))
+		}
+	}
+
+	names, err := exerciseNames(tx, userID, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		if entry.ExerciseID == nil {
+			continue
+		}
+		name, ok := names[*entry.ExerciseID]
+		if !ok {
+			return ErrUnknownExercise
+		}
+		if entry.ExerciseName == "" {
+			entry.ExerciseName = name
+		}
+	}
+
+	return nil
+}
+
+// exerciseNames returns the names of the given exercises that are either part
+// of the built-in library or owned by userID.
+func exerciseNames(tx *sql.Tx, userID int, ids []int64) (map[int]string, error) {
+	names := make(map[int]string, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	query := `
+		SELECT id, name FROM exercises
+		WHERE id = ANY($1) AND (user_id IS NULL OR user_id = $2)
+	`
+	rows, err := tx.Query(query, ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var name string
+		err = rows.Scan(&id, &name)
+		if err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+
+	return names, rows.Err()
+}
+
+// DeleteWorkout moves the workout to the trash if it is still at version, or
+// whatever its version when version is 0. The change is recorded as made by
+// actorID.
+func (pg *PostgresWorkoutStore) DeleteWorkout(id int64, version int, actorID int) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := snapshotForUpdate(tx, id)
+	if err != nil {
+		return err
+	}
+	if before == nil || before.DeletedAt != nil {
+		return sql.ErrNoRows
+	}
+	if version != 0 && version != before.Version {
+		return ErrEditConflict
+	}
+
+	query := `
+		UPDATE workouts
+		SET deleted_at = CURRENT_TIMESTAMP, version = version + 1
+		WHERE id = $1
+	`
+	_, err = tx.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	err = recordRevision(tx, id, actorID, RevisionDelete, before)
+	if err != nil {
+		return err
 	}
 
 	return tx.Commit()
 }
 
-func (pg *PostgresWorkoutStore) DeleteWorkout(id int64) error {
+// ListDeletedWorkouts returns the workouts of the user that are in the trash,
+// most recently deleted first.
+func (pg *PostgresWorkoutStore) ListDeletedWorkouts(userID int) ([]*Workout, error) {
 	query := `
-		DELETE FROM workouts WHERE id = $1
+		SELECT id, user_id, title, description, duration_minutes, calories_burned,
+			version, performed_at, created_at, updated_at, deleted_at
+		FROM workouts
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC
 	`
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	result, err := pg.db.Exec(query, id)
+	workouts := []*Workout{}
+	for rows.Next() {
+		workout := &Workout{}
+		err = rows.Scan(
+			&workout.ID,
+			&workout.UserID,
+			&workout.Title,
+			&workout.Description,
+			&workout.DurationMinutes,
+			&workout.CaloriesBurned,
+			&workout.Version,
+			&workout.PerformedAt,
+			&workout.CreatedAt,
+			&workout.UpdatedAt,
+			&workout.DeletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		workouts = append(workouts, workout)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = loadWorkoutDetails(pg.db, workouts)
+	if err != nil {
+		return nil, err
+	}
+
+	return workouts, nil
+}
+
+// RestoreWorkout takes a workout of the user out of the trash. It returns
+// sql.ErrNoRows when the user has no such workout in the trash.
+func (pg *PostgresWorkoutStore) RestoreWorkout(id int64, userID int) error {
+	tx, err := pg.db.Begin()
 	if err != nil {
 		return err
 	}
-	resultRows, err := result.RowsAffected()
+	defer tx.Rollback()
+
+	before, err := snapshotForUpdate(tx, id)
 	if err != nil {
 		return err
 	}
-	if resultRows == 0 {
+	if before == nil || before.UserID != userID || before.DeletedAt == nil {
 		return sql.ErrNoRows
 	}
 
-	return nil
+	query := `
+		UPDATE workouts
+		SET deleted_at = NULL, version = version + 1
+		WHERE id = $1
+	`
+	_, err = tx.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	err = recordRevision(tx, id, userID, RevisionRestore, before)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PurgeDeletedWorkouts permanently removes the workouts that were moved to
+// the trash before deletedBefore, along with their entries, and returns how
+// many were removed.
+func (pg *PostgresWorkoutStore) PurgeDeletedWorkouts(deletedBefore time.Time) (int64, error) {
+	result, err := pg.db.Exec(`DELETE FROM workouts WHERE deleted_at < $1`, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 func (pg *PostgresWorkoutStore) GetWorkoutOwner(workoutID int64) (int, error) {
 	query := `
-		SELECT user_id FROM workouts WHERE id = $1
+		SELECT user_id FROM workouts WHERE id = $1 AND deleted_at IS NULL
 	`
 	var userID int
 	err := pg.db.QueryRow(query, workoutID).Scan(&userID)
@@ -234,3 +976,27 @@ func (pg *PostgresWorkoutStore) GetWorkoutOwner(workoutID int64) (int, error) {
 
 	return userID, nil
 }
+
+// WorkoutExists reports whether the user has a workout, outside of the trash,
+// with the title that was performed at the given time. Times are compared to
+// the second, as files often leave out the fractions.
+func (pg *PostgresWorkoutStore) WorkoutExists(userID int, title string, performedAt time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM workouts
+			WHERE user_id = $1 AND title = $2 AND deleted_at IS NULL
+			AND date_trunc('second', performed_at) = date_trunc('second', $3::timestamptz)
+		)
+	`
+	var exists bool
+	err := pg.db.QueryRow(query, userID, title, performedAt).Scan(&exists)
+	return exists, err
+}
+
+// nullTime maps the zero time to NULL so the column default applies.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func setupTestDB(t *testing.T) *sql.DB {
@@ -58,6 +59,22 @@ func TestCreateWorkout(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "workout with a superset",
+			workout: &Workout{
+				Title:           "arm day",
+				DurationMinutes: 45,
+				Groups: []WorkoutGroup{
+					{Label: "A", GroupType: GroupTypeSuperset, Rounds: 3, RestSeconds: createIntPtr(90)},
+				},
+				Entries: []WorkoutEntry{
+					{ExerciseName: "Dips", Sets: 3, Reps: createIntPtr(12), OrderIndex: 3},
+					{ExerciseName: "Barbell Curl", Sets: 3, Reps: createIntPtr(10), OrderIndex: 1, GroupLabel: "A"},
+					{ExerciseName: "Skull Crusher", Sets: 3, Reps: createIntPtr(10), OrderIndex: 2, GroupLabel: "A"},
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "workout with invalid entries",
 			workout: &Workout{
@@ -117,6 +134,10 @@ func TestCreateWorkout(t *testing.T) {
 			assert.Equal(t, got.DurationMinutes, savedWorkout.DurationMinutes)
 			assert.Equal(t, got.CaloriesBurned, savedWorkout.CaloriesBurned)
 			assert.Equal(t, len(got.Entries), len(savedWorkout.Entries))
+			assert.False(t, savedWorkout.PerformedAt.IsZero())
+			assert.True(t, got.PerformedAt.Equal(savedWorkout.PerformedAt))
+			assert.True(t, got.CreatedAt.Equal(savedWorkout.CreatedAt))
+			assert.Equal(t, got.Groups, savedWorkout.Groups)
 
 			for i, entry := range got.Entries {
 				assert.Equal(t, entry, savedWorkout.Entries[i])
@@ -132,6 +153,196 @@ func TestCreateWorkout(t *testing.T) {
 	}
 }
 
+func createTestUser(t *testing.T, db *sql.DB, username string) *User {
+	user := &User{Username: username, Email: username + "@example.com"}
+	require.NoError(t, user.PasswordHash.Set("password123"))
+	require.NoError(t, NewPostgresUserStore(db).CreateUser(user))
+	return user
+}
+
+func TestListWorkouts(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	store := NewPostgresWorkoutStore(db)
+	user := createTestUser(t, db, "list_user")
+	other := createTestUser(t, db, "other_user")
+
+	for i, title := range []string{"push day", "pull day", "leg day"} {
+		_, err := store.CreateWorkout(&Workout{
+			UserID:          user.ID,
+			Title:           title,
+			DurationMinutes: 30 * (i + 1),
+			CaloriesBurned:  100 * (i + 1),
+			Entries: []WorkoutEntry{
+				{ExerciseName: "Squats", Sets: 3, Reps: createIntPtr(10), OrderIndex: 1},
+			},
+		})
+		require.NoError(t, err)
+	}
+	_, err := store.CreateWorkout(&Workout{UserID: other.ID, Title: "push day", DurationMinutes: 45})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		filter     WorkoutFilter
+		wantTitles []string
+		wantTotal  int
+	}{
+		{
+			name:       "sorted by duration descending",
+			filter:     WorkoutFilter{Page: 1, Limit: 10, Sort: "-duration"},
+			wantTitles: []string{"leg day", "pull day", "push day"},
+			wantTotal:  3,
+		},
+		{
+			name:       "paginated",
+			filter:     WorkoutFilter{Page: 2, Limit: 2, Sort: "calories"},
+			wantTitles: []string{"leg day"},
+			wantTotal:  3,
+		},
+		{
+			name:       "title substring and minimum duration",
+			filter:     WorkoutFilter{Page: 1, Limit: 10, Sort: "date", Title: "DAY", MinDuration: createIntPtr(60)},
+			wantTitles: []string{"pull day", "leg day"},
+			wantTotal:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.UserID = user.ID
+			workouts, metadata, err := store.ListWorkouts(tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTotal, metadata.TotalRecords)
+
+			titles := []string{}
+			for _, workout := range workouts {
+				titles = append(titles, workout.Title)
+				assert.Equal(t, user.ID, workout.UserID)
+				assert.Len(t, workout.Entries, 1)
+			}
+			assert.Equal(t, tt.wantTitles, titles)
+		})
+	}
+}
+
+func TestUpdateWorkoutReconcilesEntries(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	store := NewPostgresWorkoutStore(db)
+	user := createTestUser(t, db, "reconcile_user")
+
+	workout, err := store.CreateWorkout(&Workout{
+		UserID: user.ID,
+		Title:  "push day",
+		Entries: []WorkoutEntry{
+			{ExerciseName: "Bench Press", Sets: 3, Reps: createIntPtr(8), Weight: createFloatPtr(80), OrderIndex: 1},
+			{ExerciseName: "Dips", Sets: 3, Reps: createIntPtr(12), OrderIndex: 2},
+			{ExerciseName: "Push Ups", Sets: 2, Reps: createIntPtr(20), OrderIndex: 3},
+		},
+	})
+	require.NoError(t, err)
+	benchID, pushUpsID := workout.Entries[0].ID, workout.Entries[2].ID
+
+	// keep the bench press with a new weight, drop the dips, keep the push
+	// ups in first place and add an overhead press
+	workout.Entries = []WorkoutEntry{
+		{ID: pushUpsID, ExerciseName: "Push Ups", Sets: 2, Reps: createIntPtr(20), OrderIndex: 1},
+		{ID: benchID, ExerciseName: "Bench Press", Sets: 3, Reps: createIntPtr(8), Weight: createFloatPtr(82.5), OrderIndex: 2},
+		{ExerciseName: "Overhead Press", Sets: 3, Reps: createIntPtr(8), Weight: createFloatPtr(50), OrderIndex: 3},
+	}
+	require.NoError(t, store.UpdateWorkout(workout, user.ID))
+
+	saved, err := store.GetWorkoutByID(int64(workout.ID))
+	require.NoError(t, err)
+	require.Len(t, saved.Entries, 3)
+	assert.Equal(t, pushUpsID, saved.Entries[0].ID)
+	assert.Equal(t, benchID, saved.Entries[1].ID)
+	assert.Equal(t, 82.5, *saved.Entries[1].Weight)
+	assert.Len(t, saved.Entries[1].SetDetails, 3)
+	assert.NotZero(t, saved.Entries[2].ID)
+	assert.Equal(t, workout.Entries[2].ID, saved.Entries[2].ID)
+
+	workout.Entries = []WorkoutEntry{{ID: 1 << 30, ExerciseName: "Squats", Sets: 1, Reps: createIntPtr(5), OrderIndex: 1}}
+	assert.ErrorIs(t, store.UpdateWorkout(workout, user.ID), ErrInvalidEntry)
+}
+
+func TestUpdateWorkoutChecksVersion(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	store := NewPostgresWorkoutStore(db)
+	user := createTestUser(t, db, "version_user")
+
+	workout, err := store.CreateWorkout(&Workout{UserID: user.ID, Title: "leg day"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, workout.Version)
+
+	stale := *workout
+	workout.Title = "heavy leg day"
+	require.NoError(t, store.UpdateWorkout(workout, user.ID))
+	assert.Equal(t, 2, workout.Version)
+
+	stale.Title = "light leg day"
+	assert.ErrorIs(t, store.UpdateWorkout(&stale, user.ID), ErrEditConflict)
+	assert.ErrorIs(t, store.DeleteWorkout(int64(workout.ID), 1, user.ID), ErrEditConflict)
+
+	saved, err := store.GetWorkoutByID(int64(workout.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "heavy leg day", saved.Title)
+	assert.Equal(t, 2, saved.Version)
+
+	require.NoError(t, store.DeleteWorkout(int64(workout.ID), 2, user.ID))
+	assert.ErrorIs(t, store.DeleteWorkout(int64(workout.ID), 0, user.ID), sql.ErrNoRows)
+}
+
+func TestDeleteWorkoutMovesToTrash(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	store := NewPostgresWorkoutStore(db)
+	user := createTestUser(t, db, "trash_user")
+
+	workout, err := store.CreateWorkout(&Workout{
+		UserID:  user.ID,
+		Title:   "back day",
+		Entries: []WorkoutEntry{{ExerciseName: "Rows", Sets: 3, Reps: createIntPtr(10), OrderIndex: 1}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, store.DeleteWorkout(int64(workout.ID), 0, user.ID))
+
+	saved, err := store.GetWorkoutByID(int64(workout.ID))
+	require.NoError(t, err)
+	assert.Nil(t, saved)
+
+	trash, err := store.ListDeletedWorkouts(user.ID)
+	require.NoError(t, err)
+	require.Len(t, trash, 1)
+	assert.NotNil(t, trash[0].DeletedAt)
+	assert.Len(t, trash[0].Entries, 1)
+
+	assert.ErrorIs(t, store.RestoreWorkout(int64(workout.ID), user.ID+1), sql.ErrNoRows)
+	require.NoError(t, store.RestoreWorkout(int64(workout.ID), user.ID))
+	saved, err = store.GetWorkoutByID(int64(workout.ID))
+	require.NoError(t, err)
+	require.NotNil(t, saved)
+	assert.Len(t, saved.Entries, 1)
+
+	require.NoError(t, store.DeleteWorkout(int64(workout.ID), 0, user.ID))
+	purged, err := store.PurgeDeletedWorkouts(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+	purged, err = store.PurgeDeletedWorkouts(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, purged)
+
+	trash, err = store.ListDeletedWorkouts(user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, trash)
+}
+
 func createIntPtr(i int) *int {
 	return &i
 }
@@ -0,0 +1,231 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrInvalidTag = errors.New("invalid tag")
+
+// TagCount is a tag of a user with the number of workouts carrying it,
+// not counting workouts in the trash.
+type TagCount struct {
+	Name     string `json:"name"`
+	Workouts int    `json:"workouts"`
+}
+
+// NormalizeTags trims and lower-cases the tag names and drops duplicates, so
+// "Deload" and "deload " name the same tag.
+func NormalizeTags(names []string) ([]string, error) {
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		tag := strings.ToLower(strings.Join(strings.Fields(name), " "))
+		if tag == "" || utf8.RuneCountInString(tag) > 50 {
+			return nil, fmt.Errorf("%w: tags must be between 1 and 50 characters", ErrInvalidTag)
+		}
+		if strings.Contains(tag, ",") {
+			return nil, fmt.Errorf("%w: tags must not contain commas", ErrInvalidTag)
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// sortedTags normalizes the tag names and sorts them like loadTags does.
+func sortedTags(names []string) ([]string, error) {
+	tags, err := NormalizeTags(names)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(tags)
+	return tags, nil
+}
+
+// addTags tags the workout of userID, creating the tags the user does not
+// have yet.
+func addTags(tx *sql.Tx, userID int, workoutID int64, names []string) error {
+	tags, err := NormalizeTags(names)
+	if err != nil || len(tags) == 0 {
+		return err
+	}
+
+	query := `
+		INSERT INTO tags (user_id, name)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT (user_id, name) DO NOTHING
+	`
+	_, err = tx.Exec(query, userID, tags)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO workout_tags (workout_id, tag_id)
+		SELECT $3, id FROM tags WHERE user_id = $1 AND name = ANY($2)
+		ON CONFLICT (workout_id, tag_id) DO NOTHING
+	`
+	_, err = tx.Exec(query, userID, tags, workoutID)
+	return err
+}
+
+// setTags makes names the exact set of tags of the workout of userID.
+func setTags(tx *sql.Tx, userID int, workoutID int64, names []string) error {
+	tags, err := NormalizeTags(names)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		DELETE FROM workout_tags
+		WHERE workout_id = $1 AND tag_id NOT IN (SELECT id FROM tags WHERE user_id = $2 AND name = ANY($3))
+	`
+	_, err = tx.Exec(query, workoutID, userID, tags)
+	if err != nil {
+		return err
+	}
+
+	return addTags(tx, userID, workoutID, tags)
+}
+
+// AddWorkoutTags attaches the tags to the workout, keeping the tags it
+// already has. It returns ErrEditConflict unless the stored workout is still
+// at workout.Version, sets the new version on workout and records the change
+// as made by actorID.
+func (pg *PostgresWorkoutStore) AddWorkoutTags(workout *Workout, names []string, actorID int) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockWorkoutVersion(tx, workout)
+	if err != nil {
+		return err
+	}
+
+	err = addTags(tx, before.UserID, int64(workout.ID), names)
+	if err != nil {
+		return err
+	}
+
+	err = touchWorkout(tx, workout, before, actorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveWorkoutTag detaches a tag from the workout. The tag itself is kept.
+// It returns sql.ErrNoRows when the workout does not carry the tag, and
+// checks the version like AddWorkoutTags.
+func (pg *PostgresWorkoutStore) RemoveWorkoutTag(workout *Workout, name string, actorID int) error {
+	tags, err := NormalizeTags([]string{name})
+	if err != nil {
+		return err
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := lockWorkoutVersion(tx, workout)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		DELETE FROM workout_tags wt
+		USING tags t
+		WHERE wt.tag_id = t.id AND wt.workout_id = $1 AND t.user_id = $2 AND t.name = $3
+	`
+	result, err := tx.Exec(query, workout.ID, before.UserID, tags[0])
+	if err != nil {
+		return err
+	}
+	resultRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if resultRows == 0 {
+		return sql.ErrNoRows
+	}
+
+	err = touchWorkout(tx, workout, before, actorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListTags returns the tags of the user by name with their workout counts.
+func (pg *PostgresWorkoutStore) ListTags(userID int) ([]TagCount, error) {
+	query := `
+		SELECT t.name, count(w.id)
+		FROM tags t
+		LEFT JOIN workout_tags wt ON wt.tag_id = t.id
+		LEFT JOIN workouts w ON w.id = wt.workout_id AND w.deleted_at IS NULL
+		WHERE t.user_id = $1
+		GROUP BY t.id, t.name
+		ORDER BY t.name
+	`
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []TagCount{}
+	for rows.Next() {
+		var tag TagCount
+		err = rows.Scan(&tag.Name, &tag.Workouts)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// loadTags fetches the tag names of every given workout, keyed by workout id.
+func loadTags(q queryer, workoutIDs []int64) (map[int][]string, error) {
+	tags := make(map[int][]string, len(workoutIDs))
+	if len(workoutIDs) == 0 {
+		return tags, nil
+	}
+
+	query := `
+		SELECT wt.workout_id, t.name
+		FROM workout_tags wt
+		JOIN tags t ON t.id = wt.tag_id
+		WHERE wt.workout_id = ANY($1)
+		ORDER BY wt.workout_id, t.name
+	`
+	rows, err := q.Query(query, workoutIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workoutID int
+		var name string
+		err = rows.Scan(&workoutID, &name)
+		if err != nil {
+			return nil, err
+		}
+		tags[workoutID] = append(tags[workoutID], name)
+	}
+
+	return tags, rows.Err()
+}
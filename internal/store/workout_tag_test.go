@@ -0,0 +1,71 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{" Deload", "competition   prep", "deload"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deload", "competition prep"}, tags)
+
+	_, err = NormalizeTags([]string{"  "})
+	assert.ErrorIs(t, err, ErrInvalidTag)
+	_, err = NormalizeTags([]string{"a,b"})
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
+
+func TestWorkoutTags(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	store := NewPostgresWorkoutStore(db)
+	user := createTestUser(t, db, "tag_user")
+
+	travel, err := store.CreateWorkout(&Workout{UserID: user.ID, Title: "hotel gym", Tags: []string{"Travel", "deload"}})
+	require.NoError(t, err)
+	deload, err := store.CreateWorkout(&Workout{UserID: user.ID, Title: "light day", Tags: []string{"deload"}})
+	require.NoError(t, err)
+	heavy, err := store.CreateWorkout(&Workout{UserID: user.ID, Title: "heavy day"})
+	require.NoError(t, err)
+	// workouts without tags have an empty list, not null
+	assert.Equal(t, []string{}, heavy.Tags)
+	assert.Equal(t, []string{"deload", "travel"}, travel.Tags)
+
+	saved, err := store.GetWorkoutByID(int64(travel.ID))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deload", "travel"}, saved.Tags)
+	assert.Equal(t, 1, saved.Version)
+	saved, err = store.GetWorkoutByID(int64(heavy.ID))
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, saved.Tags)
+
+	filter := WorkoutFilter{UserID: user.ID, Page: 1, Limit: 10, Sort: "date", Tags: []string{"travel", "deload"}}
+	workouts, _, err := store.ListWorkouts(filter)
+	require.NoError(t, err)
+	assert.Len(t, workouts, 2)
+
+	filter.MatchAllTags = true
+	workouts, _, err = store.ListWorkouts(filter)
+	require.NoError(t, err)
+	require.Len(t, workouts, 1)
+	assert.Equal(t, travel.ID, workouts[0].ID)
+
+	require.NoError(t, store.AddWorkoutTags(deload, []string{"competition prep"}, user.ID))
+	assert.Equal(t, 2, deload.Version)
+	require.NoError(t, store.RemoveWorkoutTag(travel, "DELOAD", user.ID))
+
+	stale := *travel
+	stale.Version = 1
+	assert.ErrorIs(t, store.AddWorkoutTags(&stale, []string{"hotel"}, user.ID), ErrEditConflict)
+
+	tags, err := store.ListTags(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []TagCount{
+		{Name: "competition prep", Workouts: 1},
+		{Name: "deload", Workouts: 1},
+		{Name: "travel", Workouts: 1},
+	}, tags)
+}
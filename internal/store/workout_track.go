@@ -0,0 +1,133 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// trackPointBatchSize keeps the statements that insert track points well
+// below the limit of 65535 parameters.
+const trackPointBatchSize = 5000
+
+// TrackPoint is a point of a recorded GPS track. Any of its values may be
+// missing, indoor recordings for example have no position.
+type TrackPoint struct {
+	Segment         int        `json:"segment"`
+	RecordedAt      *time.Time `json:"recorded_at"`
+	Latitude        *float64   `json:"latitude"`
+	Longitude       *float64   `json:"longitude"`
+	ElevationMeters *float64   `json:"elevation_meters"`
+	HeartRate       *int       `json:"heart_rate"`
+}
+
+var trackPointColumns = []string{
+	"workout_id", "point_index", "segment", "recorded_at", "latitude", "longitude", "elevation_meters", "heart_rate",
+}
+
+var trackPointColumnTypes = []string{
+	"bigint", "integer", "integer", "timestamptz", "double precision", "double precision", "double precision", "integer",
+}
+
+// CreateWorkoutWithTrack creates the workout along with the track it was
+// recorded with.
+func (pg *PostgresWorkoutStore) CreateWorkoutWithTrack(workout *Workout, points []TrackPoint) (*Workout, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = createWorkout(tx, workout)
+	if err != nil {
+		return nil, err
+	}
+
+	err = insertTrackPoints(tx, workout.ID, points)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return workout, nil
+}
+
+func insertTrackPoints(tx *sql.Tx, workoutID int, points []TrackPoint) error {
+	for start := 0; start < len(points); start += trackPointBatchSize {
+		batch := points[start:min(start+trackPointBatchSize, len(points))]
+
+		args := make([]any, 0, len(batch)*len(trackPointColumns))
+		for i, point := range batch {
+			args = append(args,
+				workoutID,
+				start+i,
+				point.Segment,
+				point.RecordedAt,
+				point.Latitude,
+				point.Longitude,
+				point.ElevationMeters,
+				point.HeartRate,
+			)
+		}
+
+		query := fmt.Sprintf(`
+			INSERT INTO workout_track_points (%s)
+			VALUES %s
+		`, strings.Join(trackPointColumns, ", "), valuesList(len(batch), trackPointColumnTypes, 0))
+		_, err := tx.Exec(query, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListTrackPoints returns the track of the workout in recording order, or an
+// empty list when the workout was not recorded with one, along with the
+// number of points of the whole track. Longer tracks than maxPoints are
+// thinned out to every n-th point, keeping the last one so the track still
+// ends where it did.
+func (pg *PostgresWorkoutStore) ListTrackPoints(workoutID int64, maxPoints int) ([]TrackPoint, int, error) {
+	query := `
+		SELECT c.total, p.segment, p.recorded_at, p.latitude, p.longitude, p.elevation_meters, p.heart_rate
+		FROM workout_track_points p, (
+			SELECT count(*) AS total, GREATEST(1, ceil((count(*) - 1)::numeric / GREATEST($2 - 1, 1)))::integer AS step
+			FROM workout_track_points
+			WHERE workout_id = $1
+		) c
+		WHERE p.workout_id = $1 AND (p.point_index % c.step = 0 OR p.point_index = c.total - 1)
+		ORDER BY p.point_index
+	`
+	rows, err := pg.db.Query(query, workoutID, maxPoints)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	total := 0
+	points := []TrackPoint{}
+	for rows.Next() {
+		var point TrackPoint
+		err = rows.Scan(
+			&total,
+			&point.Segment,
+			&point.RecordedAt,
+			&point.Latitude,
+			&point.Longitude,
+			&point.ElevationMeters,
+			&point.HeartRate,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		points = append(points, point)
+	}
+
+	return points, total, rows.Err()
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestCreateWorkoutWithTrack(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	store := NewPostgresWorkoutStore(db)
+	user := createTestUser(t, db, "track_runner")
+
+	start := time.Date(2024, 3, 1, 7, 0, 0, 0, time.UTC)
+	later := start.Add(10 * time.Minute)
+	points := []TrackPoint{
+		{RecordedAt: &start, Latitude: createFloatPtr(52.1), Longitude: createFloatPtr(4.3), ElevationMeters: createFloatPtr(2), HeartRate: createIntPtr(110)},
+		{RecordedAt: &later, Latitude: createFloatPtr(52.12), Longitude: createFloatPtr(4.31)},
+		{Segment: 1},
+	}
+
+	workout, err := store.CreateWorkoutWithTrack(&Workout{
+		UserID:      user.ID,
+		Title:       "morning run",
+		PerformedAt: start,
+		Entries: []WorkoutEntry{
+			{ExerciseName: "Running", EntryType: EntryTypeCardio, DurationSeconds: createIntPtr(600), Distance: createFloatPtr(2.3), DistanceUnit: "km", OrderIndex: 1},
+		},
+	}, points)
+	require.NoError(t, err)
+	require.Len(t, workout.Entries, 1)
+	assert.NotNil(t, workout.Entries[0].Pace)
+
+	stored, total, err := store.ListTrackPoints(int64(workout.ID), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	require.Len(t, stored, 3)
+	assert.True(t, start.Equal(*stored[0].RecordedAt))
+	assert.Equal(t, 52.1, *stored[0].Latitude)
+	assert.Equal(t, 110, *stored[0].HeartRate)
+	assert.Nil(t, stored[1].HeartRate)
+	assert.Equal(t, 1, stored[2].Segment)
+	assert.Nil(t, stored[2].Latitude)
+
+	// workouts logged by hand have no track
+	plain, err := store.CreateWorkout(&Workout{UserID: user.ID, Title: "walk"})
+	require.NoError(t, err)
+	stored, total, err = store.ListTrackPoints(int64(plain.ID), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, stored)
+}
+
+func TestListTrackPointsThinsLongTracks(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	store := NewPostgresWorkoutStore(db)
+	user := createTestUser(t, db, "track_thinner")
+
+	points := make([]TrackPoint, 1001)
+	for i := range points {
+		points[i].HeartRate = createIntPtr(100 + i%100)
+	}
+	workout, err := store.CreateWorkoutWithTrack(&Workout{
+		UserID: user.ID,
+		Title:  "long ride",
+		Entries: []WorkoutEntry{
+			{ExerciseName: "Cycling", EntryType: EntryTypeCardio, DurationSeconds: createIntPtr(3600), OrderIndex: 1},
+		},
+	}, points)
+	require.NoError(t, err)
+
+	stored, total, err := store.ListTrackPoints(int64(workout.ID), 101)
+	require.NoError(t, err)
+	assert.Equal(t, 1001, total)
+	require.Len(t, stored, 101)
+	// every tenth point, the last one included
+	assert.Equal(t, 100, *stored[0].HeartRate)
+	assert.Equal(t, 110, *stored[1].HeartRate)
+	assert.Equal(t, 100, *stored[100].HeartRate)
+
+	stored, _, err = store.ListTrackPoints(int64(workout.ID), 2)
+	require.NoError(t, err)
+	assert.Len(t, stored, 2)
+}
@@ -0,0 +1,93 @@
+package track
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"io"
+	"strings"
+	"time"
+)
+
+// gpxPoint is a trkpt element. Heart rates come from the Garmin track point
+// extension, which most devices write.
+type gpxPoint struct {
+	Latitude  float64    `xml:"lat,attr"`
+	Longitude float64    `xml:"lon,attr"`
+	Elevation *float64   `xml:"ele"`
+	Time      *time.Time `xml:"time"`
+	HeartRate *int       `xml:"extensions>TrackPointExtension>hr"`
+}
+
+// ParseGPX reads the tracks of a GPX file. All tracks of the file make up a
+// single Track, with each of their segments a segment of it.
+func ParseGPX(r io.Reader) (*Track, error) {
+	decoder := xml.NewDecoder(r)
+	track := &Track{}
+	segment := -1
+	var path []string
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			parent := ""
+			if len(path) > 0 {
+				parent = path[len(path)-1]
+			} else if element.Name.Local != "gpx" {
+				return nil, fmt.Errorf("%w: not a GPX file", ErrInvalidFile)
+			}
+
+			switch {
+			case element.Name.Local == "trkseg":
+				segment++
+			case element.Name.Local == "trkpt":
+				if len(track.Points) == MaxPoints {
+					return nil, fmt.Errorf("%w: the file has more than %d track points", ErrInvalidFile, MaxPoints)
+				}
+				var point gpxPoint
+				err = decoder.DecodeElement(&point, &element)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+				}
+				track.Points = append(track.Points, store.TrackPoint{
+					Segment:         max(segment, 0),
+					RecordedAt:      point.Time,
+					Latitude:        &point.Latitude,
+					Longitude:       &point.Longitude,
+					ElevationMeters: point.Elevation,
+					HeartRate:       point.HeartRate,
+				})
+				continue
+			case parent == "trk" && (element.Name.Local == "name" || element.Name.Local == "type"):
+				var value string
+				err = decoder.DecodeElement(&value, &element)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+				}
+				if element.Name.Local == "name" && track.Name == "" {
+					track.Name = strings.TrimSpace(value)
+				} else if element.Name.Local == "type" && track.Sport == "" {
+					track.Sport = strings.TrimSpace(value)
+				}
+				continue
+			}
+			path = append(path, element.Name.Local)
+		case xml.EndElement:
+			path = path[:len(path)-1]
+		}
+	}
+
+	if len(track.Points) == 0 {
+		return nil, fmt.Errorf("%w: the file has no track points", ErrInvalidFile)
+	}
+	return track, nil
+}
@@ -0,0 +1,113 @@
+package track
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"io"
+	"math"
+	"time"
+)
+
+// tcxPoint is a Trackpoint element. Points recorded indoors have no
+// position.
+type tcxPoint struct {
+	Time      *time.Time `xml:"Time"`
+	Latitude  *float64   `xml:"Position>LatitudeDegrees"`
+	Longitude *float64   `xml:"Position>LongitudeDegrees"`
+	Altitude  *float64   `xml:"AltitudeMeters"`
+	HeartRate *int       `xml:"HeartRateBpm>Value"`
+}
+
+// ParseTCX reads the activities of a TCX file. Every Track element of the
+// laps becomes a segment, and the lap totals of time, distance and calories
+// add up to the totals of the Track.
+func ParseTCX(r io.Reader) (*Track, error) {
+	decoder := xml.NewDecoder(r)
+	track := &Track{}
+	segment := -1
+	var path []string
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			parent := ""
+			if len(path) > 0 {
+				parent = path[len(path)-1]
+			} else if element.Name.Local != "TrainingCenterDatabase" {
+				return nil, fmt.Errorf("%w: not a TCX file", ErrInvalidFile)
+			}
+
+			switch {
+			case element.Name.Local == "Activity" && track.Sport == "":
+				for _, attr := range element.Attr {
+					if attr.Name.Local == "Sport" {
+						track.Sport = attr.Value
+					}
+				}
+			case element.Name.Local == "Lap" && track.StartedAt == nil:
+				for _, attr := range element.Attr {
+					if attr.Name.Local == "StartTime" {
+						startedAt, err := time.Parse(time.RFC3339, attr.Value)
+						if err != nil {
+							return nil, fmt.Errorf("%w: invalid lap start time %q", ErrInvalidFile, attr.Value)
+						}
+						track.StartedAt = &startedAt
+					}
+				}
+			case element.Name.Local == "Track":
+				segment++
+			case element.Name.Local == "Trackpoint":
+				if len(track.Points) == MaxPoints {
+					return nil, fmt.Errorf("%w: the file has more than %d track points", ErrInvalidFile, MaxPoints)
+				}
+				var point tcxPoint
+				err = decoder.DecodeElement(&point, &element)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+				}
+				track.Points = append(track.Points, store.TrackPoint{
+					Segment:         max(segment, 0),
+					RecordedAt:      point.Time,
+					Latitude:        point.Latitude,
+					Longitude:       point.Longitude,
+					ElevationMeters: point.Altitude,
+					HeartRate:       point.HeartRate,
+				})
+				continue
+			case parent == "Lap" && (element.Name.Local == "TotalTimeSeconds" || element.Name.Local == "DistanceMeters" || element.Name.Local == "Calories"):
+				var value float64
+				err = decoder.DecodeElement(&value, &element)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+				}
+				switch element.Name.Local {
+				case "TotalTimeSeconds":
+					track.DurationSeconds += value
+				case "DistanceMeters":
+					track.DistanceMeters += value
+				case "Calories":
+					track.Calories += int(math.Round(value))
+				}
+				continue
+			}
+			path = append(path, element.Name.Local)
+		case xml.EndElement:
+			path = path[:len(path)-1]
+		}
+	}
+
+	if len(track.Points) == 0 && track.DurationSeconds == 0 {
+		return nil, fmt.Errorf("%w: the file has no activity", ErrInvalidFile)
+	}
+	return track, nil
+}
@@ -0,0 +1,229 @@
+package track
+
+import (
+	"errors"
+	"fmt"
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"math"
+	"strings"
+	"time"
+)
+
+var ErrInvalidFile = errors.New("invalid track file")
+
+// earthRadiusMeters is the mean radius of the earth.
+const earthRadiusMeters = 6371008.8
+
+// elevationThresholdMeters is the change in elevation needed before it counts
+// towards the gain, so the noise of GPS and barometer readings does not add
+// up over a long track.
+const elevationThresholdMeters = 3
+
+// MaxPoints caps the points of a track file, which are all stored with the
+// workout.
+const MaxPoints = 100000
+
+// MaxTitleLength is the longest title, in characters, a workout can have.
+const MaxTitleLength = 255
+
+// Track is a recorded activity as read from a GPX or TCX file.
+type Track struct {
+	Name   string
+	Sport  string
+	Points []store.TrackPoint
+	// StartedAt, DurationSeconds, DistanceMeters and Calories are the totals
+	// the file states itself, as TCX laps do, and zero when it does not.
+	StartedAt       *time.Time
+	DurationSeconds float64
+	DistanceMeters  float64
+	Calories        int
+}
+
+// Summary holds the figures derived from a track. The pointers are nil when
+// the track has no data for them.
+type Summary struct {
+	StartedAt           *time.Time
+	DurationSeconds     int
+	DistanceMeters      float64
+	ElevationGainMeters *float64
+	AvgHeartRate        *int
+	MaxHeartRate        *int
+}
+
+// Summarize derives the summary of the track. Totals stated by the file win
+// over the ones computed from the points. Duration, distance and elevation
+// gain only add up between consecutive points of the same segment, so the
+// pauses between segments do not count. Elevation only counts once it
+// changed by elevationThresholdMeters, and heart rates outside of 20 to 250
+// are taken for sensor errors and ignored.
+func (t *Track) Summarize() Summary {
+	var summary Summary
+	var first *time.Time
+	var previous, previousTimed *store.TrackPoint
+	// reference is the elevation the gain is measured from in the segment
+	var reference *float64
+	var moving time.Duration
+	var distance, gain float64
+	hasElevation := false
+	heartRates, heartRateSum, maxHeartRate := 0, 0, 0
+
+	for i := range t.Points {
+		point := &t.Points[i]
+		if point.RecordedAt != nil {
+			if first == nil || point.RecordedAt.Before(*first) {
+				first = point.RecordedAt
+			}
+			if previousTimed != nil && previousTimed.Segment == point.Segment && point.RecordedAt.After(*previousTimed.RecordedAt) {
+				moving += point.RecordedAt.Sub(*previousTimed.RecordedAt)
+			}
+			previousTimed = point
+		}
+
+		if point.HeartRate != nil && *point.HeartRate >= 20 && *point.HeartRate <= 250 {
+			heartRates++
+			heartRateSum += *point.HeartRate
+			maxHeartRate = max(maxHeartRate, *point.HeartRate)
+		}
+		if previous != nil && previous.Segment != point.Segment {
+			reference = nil
+		}
+		if point.ElevationMeters != nil {
+			hasElevation = true
+			elevation := *point.ElevationMeters
+			switch {
+			case reference == nil:
+				reference = &elevation
+			case elevation-*reference >= elevationThresholdMeters:
+				gain += elevation - *reference
+				reference = &elevation
+			case *reference-elevation >= elevationThresholdMeters:
+				reference = &elevation
+			}
+		}
+
+		if previous != nil && previous.Segment == point.Segment && hasPosition(previous) && hasPosition(point) {
+			distance += haversine(*previous.Latitude, *previous.Longitude, *point.Latitude, *point.Longitude)
+		}
+		previous = point
+	}
+
+	summary.StartedAt = first
+	if t.StartedAt != nil {
+		summary.StartedAt = t.StartedAt
+	}
+	summary.DurationSeconds = int(math.Round(moving.Seconds()))
+	if t.DurationSeconds > 0 {
+		summary.DurationSeconds = int(math.Round(t.DurationSeconds))
+	}
+
+	summary.DistanceMeters = distance
+	if t.DistanceMeters > 0 {
+		summary.DistanceMeters = t.DistanceMeters
+	}
+
+	if hasElevation {
+		summary.ElevationGainMeters = &gain
+	}
+	if heartRates > 0 {
+		avg := int(math.Round(float64(heartRateSum) / float64(heartRates)))
+		summary.AvgHeartRate, summary.MaxHeartRate = &avg, &maxHeartRate
+	}
+
+	return summary
+}
+
+func hasPosition(point *store.TrackPoint) bool {
+	return point.Latitude != nil && point.Longitude != nil
+}
+
+// haversine returns the great-circle distance in meters between two points
+// given in degrees.
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := math.Pi / 180
+	dLat := (lat2 - lat1) * toRadians
+	dLon := (lon2 - lon1) * toRadians
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRadians)*math.Cos(lat2*toRadians)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(math.Min(1, a)))
+}
+
+// sportExercises maps the sports of GPX and TCX files to the cardio
+// exercises of the catalog.
+var sportExercises = map[string]string{
+	"running":  "Running",
+	"run":      "Running",
+	"jogging":  "Jogging",
+	"walking":  "Walking",
+	"walk":     "Walking",
+	"hiking":   "Walking",
+	"biking":   "Cycling",
+	"cycling":  "Cycling",
+	"ride":     "Cycling",
+	"rowing":   "Rowing",
+	"swimming": "Swimming",
+}
+
+// Exercise names the exercise of the track after its sport, Cardio when the
+// sport is missing or unknown.
+func (t *Track) Exercise() string {
+	name, ok := sportExercises[strings.ToLower(strings.TrimSpace(t.Sport))]
+	if !ok {
+		return "Cardio"
+	}
+	return name
+}
+
+// Workout builds an unsaved cardio workout for userID with a single entry
+// covering the whole track. title replaces the name of the track when it is
+// not empty, a name longer than MaxTitleLength is cut short. It fails with
+// ErrInvalidFile when the track has no duration, which every cardio entry
+// needs.
+func (t *Track) Workout(userID int, title string) (*store.Workout, error) {
+	summary := t.Summarize()
+	if summary.DurationSeconds <= 0 {
+		return nil, fmt.Errorf("%w: the track has no duration", ErrInvalidFile)
+	}
+
+	exercise := t.Exercise()
+	if title == "" {
+		title = strings.TrimSpace(t.Name)
+		if runes := []rune(title); len(runes) > MaxTitleLength {
+			title = strings.TrimSpace(string(runes[:MaxTitleLength]))
+		}
+	}
+	if title == "" {
+		title = exercise
+	}
+
+	entry := store.WorkoutEntry{
+		ExerciseName:    exercise,
+		EntryType:       store.EntryTypeCardio,
+		Sets:            1,
+		DurationSeconds: &summary.DurationSeconds,
+		AvgHeartRate:    summary.AvgHeartRate,
+		MaxHeartRate:    summary.MaxHeartRate,
+		OrderIndex:      1,
+	}
+	if summary.DistanceMeters > 0 {
+		kilometers := math.Round(summary.DistanceMeters) / 1000
+		entry.Distance = &kilometers
+		entry.DistanceUnit = "km"
+	}
+	if summary.ElevationGainMeters != nil {
+		gain := int(math.Round(*summary.ElevationGainMeters))
+		entry.ElevationGainMeters = &gain
+	}
+
+	workout := &store.Workout{
+		UserID:          userID,
+		Title:           title,
+		DurationMinutes: int(math.Round(float64(summary.DurationSeconds) / 60)),
+		CaloriesBurned:  t.Calories,
+		Entries:         []store.WorkoutEntry{entry},
+	}
+	if summary.StartedAt != nil {
+		workout.PerformedAt = *summary.StartedAt
+	}
+
+	return workout, nil
+}
@@ -0,0 +1,182 @@
+package track
+
+import (
+	"github.com/helmigandi/go-workout-api/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1"
+  xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v1">
+  <trk>
+    <name>Morning Run</name>
+    <type>running</type>
+    <trkseg>
+      <trkpt lat="0.0" lon="0.0"><ele>10</ele><time>2024-03-01T07:00:00Z</time>
+        <extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>120</gpxtpx:hr></gpxtpx:TrackPointExtension></extensions>
+      </trkpt>
+      <trkpt lat="0.0" lon="0.01"><ele>15</ele><time>2024-03-01T07:05:00Z</time>
+        <extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>150</gpxtpx:hr></gpxtpx:TrackPointExtension></extensions>
+      </trkpt>
+    </trkseg>
+    <trkseg>
+      <trkpt lat="1.0" lon="1.0"><ele>12</ele><time>2024-03-01T07:20:00Z</time></trkpt>
+      <trkpt lat="1.0" lon="1.01"><ele>14</ele><time>2024-03-01T07:25:00Z</time></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+const testTCX = `<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2">
+  <Activities>
+    <Activity Sport="Biking">
+      <Id>2024-03-02T06:00:00Z</Id>
+      <Lap StartTime="2024-03-02T06:00:00Z">
+        <TotalTimeSeconds>1800</TotalTimeSeconds>
+        <DistanceMeters>12000</DistanceMeters>
+        <Calories>300</Calories>
+        <Track>
+          <Trackpoint>
+            <Time>2024-03-02T06:00:05Z</Time>
+            <AltitudeMeters>100</AltitudeMeters>
+            <HeartRateBpm><Value>130</Value></HeartRateBpm>
+          </Trackpoint>
+          <Trackpoint>
+            <Time>2024-03-02T06:29:55Z</Time>
+            <AltitudeMeters>90</AltitudeMeters>
+            <HeartRateBpm><Value>300</Value></HeartRateBpm>
+          </Trackpoint>
+        </Track>
+      </Lap>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`
+
+func TestParseGPX(t *testing.T) {
+	recorded, err := ParseGPX(strings.NewReader(testGPX))
+	require.NoError(t, err)
+	assert.Equal(t, "Morning Run", recorded.Name)
+	assert.Equal(t, "Running", recorded.Exercise())
+	require.Len(t, recorded.Points, 4)
+	assert.Equal(t, 0, recorded.Points[1].Segment)
+	assert.Equal(t, 1, recorded.Points[2].Segment)
+	assert.Equal(t, 150, *recorded.Points[1].HeartRate)
+	assert.Nil(t, recorded.Points[2].HeartRate)
+
+	summary := recorded.Summarize()
+	// 5 minutes in each segment, the pause of 15 minutes between them not
+	// counting, nor does the gap in distance
+	assert.Equal(t, 10*60, summary.DurationSeconds)
+	assert.InDelta(t, 1112+1112, summary.DistanceMeters, 2)
+	// the rise of 2 meters in the second segment is below the threshold
+	assert.InDelta(t, 5, *summary.ElevationGainMeters, 0.001)
+	assert.Equal(t, 135, *summary.AvgHeartRate)
+	assert.Equal(t, 150, *summary.MaxHeartRate)
+
+	workout, err := recorded.Workout(1, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Morning Run", workout.Title)
+	assert.Equal(t, time.Date(2024, 3, 1, 7, 0, 0, 0, time.UTC), workout.PerformedAt)
+	assert.Equal(t, 10, workout.DurationMinutes)
+	require.Len(t, workout.Entries, 1)
+	entry := workout.Entries[0]
+	assert.Equal(t, store.EntryTypeCardio, entry.EntryType)
+	assert.Equal(t, "km", entry.DistanceUnit)
+	assert.InDelta(t, 2.224, *entry.Distance, 0.002)
+	assert.Equal(t, 5, *entry.ElevationGainMeters)
+	assert.NoError(t, entry.Validate())
+}
+
+func TestSummarizeIgnoresElevationNoise(t *testing.T) {
+	elevations := []float64{
+		// flat, with readings jumping by up to 2 meters
+		100, 102, 100.5, 101, 99, 101.5, 100, 102, 100,
+		// a climb of 10 meters with the same noise
+		101, 103, 102, 105, 104, 107, 106, 109, 110,
+	}
+	recorded := &Track{}
+	for _, elevation := range elevations {
+		recorded.Points = append(recorded.Points, store.TrackPoint{ElevationMeters: &elevation})
+	}
+
+	summary := recorded.Summarize()
+	assert.InDelta(t, 10, *summary.ElevationGainMeters, 0.001)
+}
+
+func TestWorkoutShortensLongTrackNames(t *testing.T) {
+	start := time.Date(2024, 3, 1, 7, 0, 0, 0, time.UTC)
+	end := start.Add(time.Minute)
+	recorded := &Track{
+		Name:   strings.Repeat("é", MaxTitleLength+10),
+		Points: []store.TrackPoint{{RecordedAt: &start}, {RecordedAt: &end}},
+	}
+
+	workout, err := recorded.Workout(1, "")
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("é", MaxTitleLength), workout.Title)
+}
+
+func TestParseTCX(t *testing.T) {
+	recorded, err := ParseTCX(strings.NewReader(testTCX))
+	require.NoError(t, err)
+	assert.Equal(t, "Cycling", recorded.Exercise())
+	require.Len(t, recorded.Points, 2)
+	assert.Nil(t, recorded.Points[0].Latitude)
+
+	summary := recorded.Summarize()
+	assert.Equal(t, 1800, summary.DurationSeconds)
+	assert.Equal(t, 12000.0, summary.DistanceMeters)
+	assert.Equal(t, time.Date(2024, 3, 2, 6, 0, 0, 0, time.UTC), *summary.StartedAt)
+	assert.Equal(t, 0.0, *summary.ElevationGainMeters)
+	// the reading of 300 is out of range
+	assert.Equal(t, 130, *summary.MaxHeartRate)
+
+	workout, err := recorded.Workout(1, "Commute")
+	require.NoError(t, err)
+	assert.Equal(t, "Commute", workout.Title)
+	assert.Equal(t, 300, workout.CaloriesBurned)
+	assert.Equal(t, 12.0, *workout.Entries[0].Distance)
+	assert.NoError(t, workout.Entries[0].Validate())
+}
+
+func TestParseRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func(string) error
+		file  string
+	}{
+		{name: "gpx given tcx", parse: parseGPX, file: testTCX},
+		{name: "tcx given gpx", parse: parseTCX, file: testGPX},
+		{name: "malformed gpx", parse: parseGPX, file: `<gpx><trk><trkseg><trkpt lat="1" lon="1">`},
+		{name: "gpx without points", parse: parseGPX, file: `<gpx><trk><name>Empty</name></trk></gpx>`},
+		{name: "not xml", parse: parseTCX, file: `date,exercise`},
+		{name: "too many points", parse: parseGPX, file: "<gpx><trk><trkseg>" + strings.Repeat(`<trkpt lat="1" lon="1"/>`, MaxPoints+1) + "</trkseg></trk></gpx>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.parse(tt.file), ErrInvalidFile)
+		})
+	}
+}
+
+func TestWorkoutNeedsDuration(t *testing.T) {
+	latitude, longitude := 1.0, 1.0
+	recorded := &Track{Points: []store.TrackPoint{{Latitude: &latitude, Longitude: &longitude}}}
+	_, err := recorded.Workout(1, "")
+	assert.ErrorIs(t, err, ErrInvalidFile)
+}
+
+func parseGPX(file string) error {
+	_, err := ParseGPX(strings.NewReader(file))
+	return err
+}
+
+func parseTCX(file string) error {
+	_, err := ParseTCX(strings.NewReader(file))
+	return err
+}
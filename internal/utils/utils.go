@@ -5,7 +5,10 @@ import (
 	"errors"
 	"github.com/go-chi/chi/v5"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Envelope map[string]interface{}
@@ -28,15 +31,109 @@ func WriteJSON(w http.ResponseWriter, status int, data Envelope) error {
 }
 
 func ReadIDParam(r *http.Request) (int64, error) {
-	idParam := chi.URLParam(r, "id")
-	if idParam == "" {
-		return 0, errors.New("id param is empty")
+	return ReadInt64Param(r, "id")
+}
+
+func ReadInt64Param(r *http.Request, name string) (int64, error) {
+	param := chi.URLParam(r, name)
+	if param == "" {
+		return 0, errors.New(name + " param is empty")
 	}
 
-	id, err := strconv.ParseInt(idParam, 10, 64)
+	id, err := strconv.ParseInt(param, 10, 64)
 	if err != nil {
-		return 0, errors.New("invalid id param")
+		return 0, errors.New("invalid " + name + " param")
 	}
 
 	return id, nil
 }
+
+func ReadString(qs url.Values, key string, defaultValue string) string {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	return s
+}
+
+func ReadInt(qs url.Values, key string, defaultValue int) (int, error) {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue, nil
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.New(key + " must be an integer value")
+	}
+
+	return i, nil
+}
+
+// ReadDate parses a query value in either YYYY-MM-DD or RFC 3339 format. The
+// returned bool reports whether the value was a plain date without a time.
+func ReadDate(qs url.Values, key string) (*time.Time, bool, error) {
+	s := qs.Get(key)
+	if s == "" {
+		return nil, false, nil
+	}
+
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return &t, true, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, false, errors.New(key + " must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+	}
+
+	return &t, false, nil
+}
+
+// ReadDateRange reads the from and to query values. A plain date given as to
+// includes the whole day, so the returned to is exclusive.
+func ReadDateRange(qs url.Values) (*time.Time, *time.Time, error) {
+	from, _, err := ReadDate(qs, "from")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	to, dateOnly, err := ReadDate(qs, "to")
+	if err != nil {
+		return nil, nil, err
+	}
+	if to != nil && dateOnly {
+		end := to.Add(24 * time.Hour)
+		to = &end
+	}
+
+	if from != nil && to != nil && from.After(*to) {
+		return nil, nil, errors.New("from must not be after to")
+	}
+
+	return from, to, nil
+}
+
+// ETag formats a resource version as a strong entity tag.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// IfMatch reports whether the If-Match header of the request allows a change
+// to the resource with the given entity tag. A missing header or "*" match
+// any tag, weak tags never match.
+func IfMatch(r *http.Request, etag string) bool {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == etag {
+			return true
+		}
+	}
+
+	return false
+}
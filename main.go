@@ -1,17 +1,24 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/helmigandi/go-workout-api/internal/app"
 	"github.com/helmigandi/go-workout-api/internal/routes"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
 func main() {
 	var port int
+	var trashRetention time.Duration
 	flag.IntVar(&port, "port", 8080, "port to listen on")
+	flag.DurationVar(&trashRetention, "trash-retention", 30*24*time.Hour, "how long deleted workouts stay in the trash before they are purged, 0 keeps them forever")
 	flag.Parse()
 
 	app, err := app.NewApplication()
@@ -20,6 +27,10 @@ func main() {
 	}
 	defer app.DB.Close()
 
+	if trashRetention > 0 {
+		go app.PurgeTrash(trashRetention, time.Hour)
+	}
+
 	r := routes.SetupRoutes(app)
 
 	server := &http.Server{
@@ -30,10 +41,26 @@ func main() {
 		WriteTimeout: 30 * time.Second,
 	}
 
-	app.Logger.Printf("Starting server on port %d", port)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		app.Logger.Printf("Starting server on port %d", port)
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			app.Logger.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	app.Logger.Printf("Shutting down")
 
-	err = server.ListenAndServe()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err = server.Shutdown(shutdownCtx)
 	if err != nil {
-		app.Logger.Fatal(err)
+		app.Logger.Printf("ERROR: shutdown: %v\n", err)
 	}
+	// running imports are marked as failed before the database is closed
+	app.Importer.Stop()
 }
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workouts
+ADD COLUMN performed_at TIMESTAMP WITH TIME ZONE
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE workouts SET performed_at = created_at
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE workouts
+ALTER COLUMN performed_at SET DEFAULT CURRENT_TIMESTAMP,
+ALTER COLUMN performed_at SET NOT NULL
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS idx_workouts_user_performed_at ON workouts (user_id, performed_at)
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workouts DROP COLUMN performed_at
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS exercises (
+    id                  BIGSERIAL PRIMARY KEY,
+    -- NULL for the built-in library, otherwise the owner of a custom exercise
+    user_id             BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    name                VARCHAR(255) NOT NULL,
+    category            VARCHAR(50) NOT NULL,
+    primary_muscles     TEXT[] NOT NULL DEFAULT '{}',
+    secondary_muscles   TEXT[] NOT NULL DEFAULT '{}',
+    equipment           VARCHAR(100) NOT NULL DEFAULT '',
+    created_at          TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at          TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT valid_exercise_category CHECK (
+        category IN ('strength', 'cardio', 'flexibility', 'plyometrics', 'other')
+    )
+)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exercises_library_name ON exercises (lower(name)) WHERE user_id IS NULL
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exercises_user_name ON exercises (user_id, lower(name)) WHERE user_id IS NOT NULL
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE exercises;
+-- +goose StatementEnd
@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+INSERT INTO exercises (name, category, primary_muscles, secondary_muscles, equipment) VALUES
+    ('Bench Press',             'strength',    '{chest}',                  '{triceps,shoulders}',          'barbell'),
+    ('Incline Bench Press',     'strength',    '{chest}',                  '{shoulders,triceps}',          'barbell'),
+    ('Dumbbell Bench Press',    'strength',    '{chest}',                  '{triceps,shoulders}',          'dumbbell'),
+    ('Push-Ups',                'strength',    '{chest}',                  '{triceps,shoulders,core}',     'bodyweight'),
+    ('Dips',                    'strength',    '{triceps}',                '{chest,shoulders}',            'bodyweight'),
+    ('Overhead Press',          'strength',    '{shoulders}',              '{triceps,core}',               'barbell'),
+    ('Lateral Raise',           'strength',    '{shoulders}',              '{}',                           'dumbbell'),
+    ('Deadlifts',               'strength',    '{hamstrings,glutes,back}', '{forearms,core}',              'barbell'),
+    ('Romanian Deadlift',       'strength',    '{hamstrings}',             '{glutes,back}',                'barbell'),
+    ('Pull-Ups',                'strength',    '{back}',                   '{biceps,forearms}',            'bodyweight'),
+    ('Chin-Ups',                'strength',    '{back,biceps}',            '{forearms}',                   'bodyweight'),
+    ('Barbell Row',             'strength',    '{back}',                   '{biceps,forearms}',            'barbell'),
+    ('Lat Pulldown',            'strength',    '{back}',                   '{biceps}',                     'cable'),
+    ('Seated Cable Row',        'strength',    '{back}',                   '{biceps}',                     'cable'),
+    ('Face Pull',               'strength',    '{shoulders}',              '{back}',                       'cable'),
+    ('Bicep Curl',              'strength',    '{biceps}',                 '{forearms}',                   'dumbbell'),
+    ('Hammer Curl',             'strength',    '{biceps,forearms}',        '{}',                           'dumbbell'),
+    ('Tricep Pushdown',         'strength',    '{triceps}',                '{}',                           'cable'),
+    ('Skull Crushers',          'strength',    '{triceps}',                '{}',                           'barbell'),
+    ('Squats',                  'strength',    '{quadriceps,glutes}',      '{hamstrings,core}',            'barbell'),
+    ('Front Squat',             'strength',    '{quadriceps}',             '{glutes,core}',                'barbell'),
+    ('Leg Press',               'strength',    '{quadriceps,glutes}',      '{hamstrings}',                 'machine'),
+    ('Lunges',                  'strength',    '{quadriceps,glutes}',      '{hamstrings,calves}',          'dumbbell'),
+    ('Bulgarian Split Squat',   'strength',    '{quadriceps,glutes}',      '{hamstrings}',                 'dumbbell'),
+    ('Leg Curl',                'strength',    '{hamstrings}',             '{calves}',                     'machine'),
+    ('Leg Extension',           'strength',    '{quadriceps}',             '{}',                           'machine'),
+    ('Hip Thrust',              'strength',    '{glutes}',                 '{hamstrings}',                 'barbell'),
+    ('Calf Raise',              'strength',    '{calves}',                 '{}',                           'machine'),
+    ('Plank',                   'strength',    '{core}',                   '{shoulders}',                  'bodyweight'),
+    ('Hanging Leg Raise',       'strength',    '{core}',                   '{forearms}',                   'bodyweight'),
+    ('Crunches',                'strength',    '{core}',                   '{}',                           'bodyweight'),
+    ('Kettlebell Swing',        'strength',    '{glutes,hamstrings}',      '{back,core,shoulders}',        'kettlebell'),
+    ('Box Jump',                'plyometrics', '{quadriceps,glutes}',      '{calves,hamstrings}',          'box'),
+    ('Burpees',                 'plyometrics', '{full_body}',              '{}',                           'bodyweight'),
+    ('Jump Rope',               'cardio',      '{calves}',                 '{shoulders,forearms}',         'jump rope'),
+    ('Running',                 'cardio',      '{quadriceps,hamstrings}',  '{calves,glutes}',              'none'),
+    ('Jogging',                 'cardio',      '{quadriceps,hamstrings}',  '{calves,glutes}',              'none'),
+    ('Walking',                 'cardio',      '{quadriceps}',             '{calves,glutes}',              'none'),
+    ('Cycling',                 'cardio',      '{quadriceps}',             '{hamstrings,calves,glutes}',   'bike'),
+    ('Rowing',                  'cardio',      '{back,quadriceps}',        '{biceps,hamstrings,core}',     'rowing machine'),
+    ('Swimming',                'cardio',      '{full_body}',              '{}',                           'none'),
+    ('Elliptical',              'cardio',      '{quadriceps,glutes}',      '{hamstrings,calves}',          'machine'),
+    ('Hamstring Stretch',       'flexibility', '{hamstrings}',             '{}',                           'none'),
+    ('Hip Flexor Stretch',      'flexibility', '{quadriceps}',             '{glutes}',                     'none'),
+    ('Yoga Flow',               'flexibility', '{full_body}',              '{}',                           'mat')
+ON CONFLICT (lower(name)) WHERE user_id IS NULL DO NOTHING
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM exercises WHERE user_id IS NULL
+-- +goose StatementEnd
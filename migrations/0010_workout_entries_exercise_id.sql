@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workout_entries
+ADD COLUMN exercise_id BIGINT REFERENCES exercises(id) ON DELETE SET NULL
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS idx_workout_entries_exercise_id ON workout_entries (exercise_id)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- link existing free text entries to the library where the name matches
+UPDATE workout_entries e
+SET exercise_id = x.id
+FROM exercises x
+WHERE x.user_id IS NULL AND lower(x.name) = lower(e.exercise_name)
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workout_entries DROP COLUMN exercise_id
+-- +goose StatementEnd
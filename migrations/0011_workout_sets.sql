@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS workout_sets (
+    id                  BIGSERIAL PRIMARY KEY,
+    workout_entry_id    BIGINT NOT NULL REFERENCES workout_entries(id) ON DELETE CASCADE,
+    set_number          INTEGER NOT NULL,
+    reps                INTEGER,
+    weight              DECIMAL(5, 2),
+    duration_seconds    INTEGER,
+    rpe                 DECIMAL(3, 1),
+    rir                 INTEGER,
+    is_warmup           BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at          TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_workout_set_number UNIQUE (workout_entry_id, set_number),
+    CONSTRAINT valid_workout_set CHECK (reps IS NOT NULL OR duration_seconds IS NOT NULL),
+    CONSTRAINT valid_workout_set_rpe CHECK (rpe IS NULL OR rpe BETWEEN 1 AND 10),
+    CONSTRAINT valid_workout_set_rir CHECK (rir IS NULL OR rir >= 0)
+)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- expand the existing flat entries into identical sets
+INSERT INTO workout_sets (workout_entry_id, set_number, reps, weight, duration_seconds)
+SELECT e.id, n, e.reps, e.weight, e.duration_seconds
+FROM workout_entries e
+CROSS JOIN LATERAL generate_series(1, GREATEST(e.sets, 1)) AS n
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE workout_sets;
+-- +goose StatementEnd
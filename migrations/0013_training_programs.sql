@@ -0,0 +1,66 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS training_programs (
+    id                  BIGSERIAL PRIMARY KEY,
+    user_id             BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name                VARCHAR(255) NOT NULL,
+    description         TEXT NOT NULL DEFAULT '',
+    duration_weeks      INTEGER NOT NULL CHECK (duration_weeks > 0),
+    created_at          TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at          TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS program_days (
+    id                  BIGSERIAL PRIMARY KEY,
+    program_id          BIGINT NOT NULL REFERENCES training_programs(id) ON DELETE CASCADE,
+    week_number         INTEGER NOT NULL CHECK (week_number > 0),
+    day_number          INTEGER NOT NULL CHECK (day_number BETWEEN 1 AND 7),
+    template_id         BIGINT NOT NULL REFERENCES workout_templates(id) ON DELETE RESTRICT,
+    name                VARCHAR(255) NOT NULL DEFAULT '',
+    CONSTRAINT unique_program_day UNIQUE (program_id, week_number, day_number)
+)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS program_enrollments (
+    id                  BIGSERIAL PRIMARY KEY,
+    program_id          BIGINT NOT NULL REFERENCES training_programs(id) ON DELETE CASCADE,
+    user_id             BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_date          DATE NOT NULL,
+    created_at          TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS program_sessions (
+    id                  BIGSERIAL PRIMARY KEY,
+    enrollment_id       BIGINT NOT NULL REFERENCES program_enrollments(id) ON DELETE CASCADE,
+    program_day_id      BIGINT NOT NULL REFERENCES program_days(id) ON DELETE CASCADE,
+    workout_id          BIGINT NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+    completed_at        TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_program_session UNIQUE (enrollment_id, program_day_id)
+)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS idx_program_enrollments_user_id ON program_enrollments (user_id)
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE program_sessions;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE program_enrollments;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE program_days;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE training_programs;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS personal_records (
+    id                  BIGSERIAL PRIMARY KEY,
+    user_id             BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    workout_id          BIGINT NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+    workout_entry_id    BIGINT REFERENCES workout_entries(id) ON DELETE SET NULL,
+    exercise_id         BIGINT REFERENCES exercises(id) ON DELETE SET NULL,
+    exercise_name       VARCHAR(255) NOT NULL,
+    record_type         VARCHAR(30) NOT NULL,
+    value               DECIMAL(10, 2) NOT NULL,
+    -- the weight the reps were lifted at for max_reps_at_weight records
+    weight              DECIMAL(5, 2),
+    achieved_at         TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at          TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT valid_personal_record_type CHECK (
+        record_type IN ('max_weight', 'max_reps_at_weight', 'estimated_1rm', 'max_duration')
+    )
+)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS idx_personal_records_user_exercise ON personal_records (user_id, lower(exercise_name), achieved_at)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS idx_personal_records_workout_id ON personal_records (workout_id)
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE personal_records;
+-- +goose StatementEnd
@@ -0,0 +1,51 @@
+-- +goose Up
+-- weights are stored in kilograms, weight_unit records the unit an entry was logged in
+-- +goose StatementBegin
+ALTER TABLE workout_entries
+ALTER COLUMN weight TYPE DECIMAL(8, 3),
+ADD COLUMN weight_unit VARCHAR(2) NOT NULL DEFAULT 'kg' CHECK (weight_unit IN ('kg', 'lb'))
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE workout_sets ALTER COLUMN weight TYPE DECIMAL(8, 3)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE template_exercises ALTER COLUMN target_weight TYPE DECIMAL(8, 3)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE personal_records
+ALTER COLUMN weight TYPE DECIMAL(8, 3),
+ALTER COLUMN value TYPE DECIMAL(12, 3)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE users
+ADD COLUMN weight_unit VARCHAR(2) NOT NULL DEFAULT 'kg' CHECK (weight_unit IN ('kg', 'lb'))
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN weight_unit
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE personal_records
+ALTER COLUMN weight TYPE DECIMAL(5, 2),
+ALTER COLUMN value TYPE DECIMAL(10, 2)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE template_exercises ALTER COLUMN target_weight TYPE DECIMAL(5, 2)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE workout_sets ALTER COLUMN weight TYPE DECIMAL(5, 2)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE workout_entries
+ALTER COLUMN weight TYPE DECIMAL(5, 2),
+DROP COLUMN weight_unit
+-- +goose StatementEnd
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workout_entries
+ADD COLUMN entry_type VARCHAR(20) NOT NULL DEFAULT 'strength' CHECK (entry_type IN ('strength', 'cardio')),
+ADD COLUMN distance DECIMAL(10, 3) CHECK (distance > 0),
+ADD COLUMN distance_unit VARCHAR(2) CHECK (distance_unit IN ('km', 'mi')),
+ADD COLUMN elevation_gain_meters INTEGER CHECK (elevation_gain_meters >= 0),
+ADD COLUMN avg_heart_rate INTEGER CHECK (avg_heart_rate BETWEEN 20 AND 250),
+ADD COLUMN max_heart_rate INTEGER CHECK (max_heart_rate BETWEEN 20 AND 250),
+DROP CONSTRAINT valid_workout_entry,
+ADD CONSTRAINT valid_workout_entry CHECK (
+    (entry_type = 'strength' AND
+        (reps IS NOT NULL OR duration_seconds IS NOT NULL) AND
+        (reps IS NULL OR duration_seconds IS NULL) AND
+        distance IS NULL AND elevation_gain_meters IS NULL) OR
+    (entry_type = 'cardio' AND duration_seconds IS NOT NULL AND reps IS NULL)
+),
+ADD CONSTRAINT valid_workout_entry_distance CHECK ((distance IS NULL) = (distance_unit IS NULL)),
+ADD CONSTRAINT valid_workout_entry_heart_rate CHECK (avg_heart_rate <= max_heart_rate)
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workout_entries
+DROP CONSTRAINT valid_workout_entry,
+DROP COLUMN entry_type,
+DROP COLUMN distance,
+DROP COLUMN distance_unit,
+DROP COLUMN elevation_gain_meters,
+DROP COLUMN avg_heart_rate,
+DROP COLUMN max_heart_rate,
+ADD CONSTRAINT valid_workout_entry CHECK (
+    (reps IS NOT NULL OR duration_seconds IS NOT NULL) AND
+    (reps IS NULL OR duration_seconds IS NULL)
+)
+-- +goose StatementEnd
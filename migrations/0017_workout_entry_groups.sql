@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS workout_entry_groups (
+    id                  BIGSERIAL PRIMARY KEY,
+    workout_id          BIGINT NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+    label               VARCHAR(50) NOT NULL,
+    group_type          VARCHAR(20) NOT NULL CHECK (group_type IN ('superset', 'circuit')),
+    rounds              INTEGER NOT NULL DEFAULT 1 CHECK (rounds >= 1),
+    rest_seconds        INTEGER CHECK (rest_seconds >= 0),
+    created_at          TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_workout_entry_group_label UNIQUE (workout_id, label)
+)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE workout_entries
+ADD COLUMN group_label VARCHAR(50),
+ADD CONSTRAINT fk_workout_entry_group FOREIGN KEY (workout_id, group_label)
+    REFERENCES workout_entry_groups (workout_id, label)
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workout_entries DROP COLUMN group_label
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE workout_entry_groups;
+-- +goose StatementEnd
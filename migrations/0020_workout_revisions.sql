@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS workout_revisions (
+    id                  BIGSERIAL PRIMARY KEY,
+    workout_id          BIGINT NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+    -- the version of the workout after the change
+    version             INTEGER NOT NULL,
+    user_id             BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    action              VARCHAR(20) NOT NULL,
+    -- snapshots of the workout with its entries before and after the change
+    before              JSONB,
+    after               JSONB NOT NULL,
+    created_at          TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT valid_workout_revision_action CHECK (
+        action IN ('create', 'update', 'delete', 'restore', 'rollback')
+    )
+)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS idx_workout_revisions_workout_id ON workout_revisions (workout_id, id)
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE workout_revisions;
+-- +goose StatementEnd
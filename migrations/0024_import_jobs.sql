@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id                  BIGSERIAL PRIMARY KEY,
+    user_id             BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status              VARCHAR(20) NOT NULL DEFAULT 'pending'
+                        CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    filename            VARCHAR(255) NOT NULL DEFAULT '',
+    rows_processed      INTEGER NOT NULL DEFAULT 0,
+    rows_failed         INTEGER NOT NULL DEFAULT 0,
+    workouts_imported   INTEGER NOT NULL DEFAULT 0,
+    duplicates_skipped  INTEGER NOT NULL DEFAULT 0,
+    -- the first of the per-row errors, as [{"row": 2, "error": "..."}]
+    errors              JSONB NOT NULL DEFAULT '[]',
+    error               TEXT NOT NULL DEFAULT '',
+    created_at          TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    started_at          TIMESTAMP WITH TIME ZONE,
+    finished_at         TIMESTAMP WITH TIME ZONE
+)
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS idx_import_jobs_user_id ON import_jobs (user_id)
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE import_jobs;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS workout_track_points (
+    workout_id          BIGINT NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+    point_index         INTEGER NOT NULL,
+    -- points of different segments are not connected, like after pausing a recording
+    segment             INTEGER NOT NULL DEFAULT 0,
+    recorded_at         TIMESTAMP WITH TIME ZONE,
+    latitude            DOUBLE PRECISION,
+    longitude           DOUBLE PRECISION,
+    elevation_meters    DOUBLE PRECISION,
+    heart_rate          INTEGER,
+    PRIMARY KEY (workout_id, point_index)
+)
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE workout_track_points;
+-- +goose StatementEnd